@@ -1,29 +1,54 @@
 package main
 
 import (
-	"encoding/json"
-	"log"
+	"context"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/euandresimoes/ecom-go/backend/internal/domain/auth"
+	"github.com/euandresimoes/ecom-go/backend/internal/domain/cart"
+	"github.com/euandresimoes/ecom-go/backend/internal/domain/health"
+	"github.com/euandresimoes/ecom-go/backend/internal/domain/order"
 	"github.com/euandresimoes/ecom-go/backend/internal/domain/product"
+	"github.com/euandresimoes/ecom-go/backend/internal/domain/user"
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/cache"
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/logging"
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/mailer"
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/metrics"
 	"github.com/euandresimoes/ecom-go/backend/internal/infra/security"
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/storage"
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/tracing"
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/validation"
 	"github.com/euandresimoes/ecom-go/backend/internal/middlewares"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-playground/validator/v10"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 )
 
+// productV1Deprecated is when the query-string product routes under
+// /api/v1/product were deprecated in favour of /api/v2, and productV1Sunset
+// is when they stop being served.
+var (
+	productV1Deprecated = time.Date(2025, time.December, 26, 0, 0, 0, 0, time.UTC)
+	productV1Sunset     = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
 func (api *Api) routes() http.Handler {
 	r := chi.NewRouter()
 
 	// A good base middleware stack
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
-	r.Use(middleware.Logger)
+	r.Use(logging.Requests(api.logger))
+	r.Use(tracing.HTTP)
+	r.Use(metrics.HTTP)
 	r.Use(middleware.Recoverer)
 	// Set a timeout value on the request context (ctx), that will signal
 	// through ctx.Done() that the request has timed out and further
@@ -31,44 +56,171 @@ func (api *Api) routes() http.Handler {
 	r.Use(middleware.Timeout(60 * time.Second))
 	r.Use(middlewares.JSON)
 
-	// health check endpoint
-	r.Get("/api/v1/health", func(w http.ResponseWriter, r *http.Request) {
-		json.NewEncoder(w).Encode(map[string]string{
-			"status":  "ok",
-			"message": "all good!",
+	// probes: liveness only needs the process, readiness checks Postgres and
+	// Redis and fails while draining so the load balancer stops routing here
+	// before the server shuts down
+	healthHandler := health.NewHandler(api.db, api.redis, api.draining.Load, api.logger)
+	r.Get("/livez", healthHandler.Live)
+	r.Get("/readyz", healthHandler.Ready)
+	// kept for load balancers still probing the old path
+	r.Get("/api/v1/health", healthHandler.Ready)
+
+	// uploaded files, only when they are kept on local disk
+	if api.uploadsDir != "" {
+		files := http.StripPrefix("/uploads/", http.FileServerFS(os.DirFS(api.uploadsDir)))
+		r.Get("/uploads/*", func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "/") {
+				http.NotFound(w, r)
+				return
+			}
+
+			// let the file server pick the type instead of the JSON default
+			w.Header().Del("Content-Type")
+			files.ServeHTTP(w, r)
 		})
-	})
+	}
 
 	// utils
-	jwtManager := security.NewJWTManager(api.jwtSecret, api.jwtExp)
-	validator := validator.New()
+	denylist := security.NewDenylist(api.redis, api.jwtExp)
+	jwtManager := security.NewJWTManager(api.jwtSecret, api.jwtExp, denylist)
+	validator := validation.Validator()
+	store := cache.NewRedisStore(api.redis)
 
 	// handlers
-	authRepo := auth.NewRepository(api.db, api.redis, jwtManager)
-	authService := auth.NewService(authRepo)
+	authRepo := auth.NewPostgresRepository(api.db, store, jwtManager, api.refreshExp, api.requireVerifiedLogin, api.bcryptCost, api.logger)
+	authService := auth.NewService(authRepo, api.mailer, api.appURL, api.logger)
 	authHandler := auth.NewHandler(authService, validator, jwtManager)
 	r.Mount("/api/v1/auth", authHandler)
 
-	productRepo := product.NewRepository(api.db, api.redis)
-	productService := product.NewService(productRepo)
+	productRepo := product.NewPostgresRepository(api.db, store, api.logger)
+	productService := product.NewService(productRepo, api.storage, api.logger)
 	productHandler := product.NewHandler(productService, validator, jwtManager)
-	r.Mount("/api/v1/product", productHandler)
+	r.With(middlewares.Deprecated(productV1Deprecated, productV1Sunset, "/api/v2/products")).Mount("/api/v1/product", productHandler)
+	r.Mount("/api/v2", product.NewHandlerV2(productService, validator, jwtManager))
+
+	cartRepo := cart.NewRepository(api.db, api.logger)
+	cartService := cart.NewService(cartRepo, api.logger)
+	cartHandler := cart.NewHandler(cartService, validator, jwtManager)
+	r.Mount("/api/v1/cart", cartHandler)
+
+	orderRepo := order.NewRepository(api.db, store, api.requireVerifiedCheckout, api.logger)
+	orderService := order.NewService(orderRepo, api.logger)
+	orderHandler := order.NewHandler(orderService, validator, jwtManager)
+	r.Mount("/api/v1/orders", orderHandler)
+
+	userRepo := user.NewRepository(api.db, jwtManager, api.logger)
+	userService := user.NewService(userRepo, api.logger)
+	userHandler := user.NewHandler(userService, validator, jwtManager)
+	r.Mount("/api/v1/admin/users", userHandler)
 
 	return r
 }
 
+// metricsRoutes is served on metricsAddr rather than the public router, so
+// scrapes stay on the internal network without needing a token.
+func (api *Api) metricsRoutes() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler())
+
+	return mux
+}
+
+// Start serves until SIGINT or SIGTERM. It then fails the readiness probe for
+// drainDelay, waits up to shutdownTimeout for in-flight requests and closes
+// the database and Redis connections, flushing pending trace spans last.
 func (api *Api) Start() {
-	r := api.routes()
-	log.Printf("server running on %s", api.addr)
-	if err := http.ListenAndServe(api.addr, r); err != nil {
-		log.Fatal(err)
+	srv := &http.Server{
+		Addr:              api.addr,
+		Handler:           api.routes(),
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       api.readTimeout,
+		WriteTimeout:      api.writeTimeout,
+		IdleTimeout:       api.idleTimeout,
+	}
+	metricsSrv := &http.Server{
+		Addr:              api.metricsAddr,
+		Handler:           api.metricsRoutes(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 2)
+	go func() {
+		api.logger.Info("server running", "addr", api.addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+	go func() {
+		api.logger.Info("metrics server running", "addr", api.metricsAddr)
+		serveErr <- metricsSrv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		api.logger.Error("server failed", "error", err)
+		os.Exit(1)
+	case <-ctx.Done():
 	}
+
+	// a second signal kills the process right away
+	stop()
+
+	api.draining.Store(true)
+	api.logger.Info("shutting down", "drain_delay", api.drainDelay.String())
+	time.Sleep(api.drainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), api.shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		api.logger.Error("graceful shutdown failed", "error", err)
+		srv.Close()
+	}
+	// scrapes are short, the metrics server is closed outright
+	metricsSrv.Close()
+
+	api.db.Close()
+	if err := api.redis.Close(); err != nil {
+		api.logger.Error("failed to close redis", "error", err)
+	}
+
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFlush()
+
+	if err := api.shutdownTracing(flushCtx); err != nil {
+		api.logger.Error("failed to flush traces", "error", err)
+	}
+
+	api.logger.Info("server stopped")
 }
 
 type Api struct {
-	addr      string
-	db        *pgxpool.Pool
-	redis     *redis.Client
-	jwtSecret string
-	jwtExp    time.Duration
+	logger      *slog.Logger
+	addr        string
+	metricsAddr string
+	db          *pgxpool.Pool
+	redis       *redis.Client
+	jwtSecret   string
+	jwtExp      time.Duration
+	refreshExp  time.Duration
+	bcryptCost  int
+	mailer      mailer.Mailer
+	appURL      string
+
+	requireVerifiedLogin    bool
+	requireVerifiedCheckout bool
+
+	storage    storage.Storage
+	uploadsDir string
+
+	readTimeout     time.Duration
+	writeTimeout    time.Duration
+	idleTimeout     time.Duration
+	drainDelay      time.Duration
+	shutdownTimeout time.Duration
+
+	shutdownTracing func(context.Context) error
+
+	draining atomic.Bool
 }
@@ -1,51 +1,152 @@
 package main
 
 import (
-	"log"
+	"context"
+	"flag"
+	"log/slog"
 	"os"
-	"time"
 
+	"github.com/euandresimoes/ecom-go/backend/internal/config"
 	"github.com/euandresimoes/ecom-go/backend/internal/infra/cache"
 	"github.com/euandresimoes/ecom-go/backend/internal/infra/database"
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/logging"
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/mailer"
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/metrics"
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/storage"
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/tracing"
 )
 
 func main() {
-	// Load env variables - Dev
-	// if err := godotenv.Load(); err != nil {
-	// 	log.Fatal("Error loading .env file")
-	// }
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "optional YAML or TOML config file")
+	flag.Parse()
 
-	envs := map[int]string{
-		0: os.Getenv("ADMIN_EMAIL"),
-		1: os.Getenv("ADMIN_PASSWORD"),
-		2: os.Getenv("API_ADDR"),
-		3: os.Getenv("DATABASE_URL"),
-		4: os.Getenv("REDIS_URL"),
-		5: os.Getenv("JWT_SECRET"),
+	// used until the configured level is known
+	slog.SetDefault(logging.New(os.Stdout, slog.LevelInfo))
+
+	// migrations only need the database, so the job is not handed the API's
+	// secrets just to pass validation
+	if flag.Arg(0) == "migrate" {
+		cfg, err := config.LoadDatabase(*configFile)
+		if err != nil {
+			fatal("invalid configuration", err)
+		}
+
+		logger := logging.New(os.Stdout, cfg.Log.Level)
+		slog.SetDefault(logger)
+
+		if err := migrate(context.Background(), logger, cfg.Database.URL.Value(), flag.Arg(1)); err != nil {
+			fatal("migration failed", err)
+		}
+		return
+	}
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		fatal("invalid configuration", err)
 	}
 
-	db, err := database.NewPostgres(envs[3])
+	logger := logging.New(os.Stdout, cfg.Log.Level)
+	slog.SetDefault(logger)
+	logger.Info("configuration loaded", "config", cfg)
+
+	cache.TTL = cfg.Cache.TTL
+
+	// set up before the clients below so their instrumentation picks up the
+	// tracer provider
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Exporter, cfg.Tracing.OTLPEndpoint, cfg.Tracing.ServiceName, cfg.Tracing.SampleRatio)
+	if err != nil {
+		fatal("failed to set up tracing", err)
+	}
+
+	db, err := database.NewPostgres(cfg.Database.URL.Value(), cfg.Database.MaxConns, cfg.Database.MinConns, cfg.Database.StatementTimeout)
 	if err != nil {
-		log.Fatalf("An error occurred while trying to connect to postgres: %s", err)
+		fatal("failed to connect to postgres", err)
 	}
+	metrics.RegisterPool(db)
 
-	err = database.CreateAdmin(envs[0], envs[1], db)
+	err = database.CreateAdmin(cfg.Admin.Email, cfg.Admin.Password.Value(), cfg.Auth.BcryptCost, db)
 	if err != nil {
-		log.Fatalf("An error occurred while trying to create admin user: %s", err)
+		fatal("failed to create admin user", err)
 	}
 
-	redis, err := cache.NewRedis(envs[4], "")
+	redis, err := cache.NewRedis(cfg.Redis.Addr, cfg.Redis.Password.Value(), cfg.Redis.PoolSize, cfg.Redis.Timeout)
 	if err != nil {
-		log.Fatalf("An error occurred while trying to connect to redis: %s", err)
+		fatal("failed to connect to redis", err)
+	}
+
+	var mail mailer.Mailer
+	switch cfg.Mail.Driver {
+	case "smtp":
+		m := cfg.Mail
+		mail = mailer.NewSMTP(m.SMTPHost, m.SMTPPort, m.SMTPUsername, m.SMTPPassword.Value(), m.From, m.SMTPTimeout)
+	case "file":
+		mail, err = mailer.NewFile(cfg.Mail.File)
+		if err != nil {
+			fatal("failed to open mail file", err)
+		}
+	case "log":
+		logger.Warn("emails are printed to stdout, tokens included; do not use MAIL_DRIVER=log in production")
+		mail = mailer.NewLog(os.Stdout)
+	}
+
+	var (
+		files      storage.Storage
+		uploadsDir string
+	)
+	switch cfg.Storage.Driver {
+	case "s3":
+		s := cfg.Storage
+		files, err = storage.NewS3(s.S3Endpoint, s.S3AccessKey, s.S3SecretKey.Value(), s.S3Bucket, s.S3Region, s.S3UseSSL, s.PublicURL)
+		if err != nil {
+			fatal("failed to connect to s3", err)
+		}
+	default:
+		uploadsDir = cfg.Storage.LocalDir
+
+		publicURL := cfg.Storage.PublicURL
+		if publicURL == "" {
+			publicURL = "/uploads"
+		}
+
+		files, err = storage.NewLocal(uploadsDir, publicURL)
+		if err != nil {
+			fatal("failed to create uploads dir", err)
+		}
 	}
 
-	api := Api{
-		addr:      envs[2],
-		db:        db,
-		redis:     redis,
-		jwtSecret: envs[5],
-		jwtExp:    time.Minute * 5,
+	api := &Api{
+		logger:     logger,
+		addr:       cfg.Server.Addr,
+		db:         db,
+		redis:      redis,
+		jwtSecret:  cfg.Auth.JWTSecret.Value(),
+		jwtExp:     cfg.Auth.AccessTokenTTL,
+		refreshExp: cfg.Auth.RefreshTokenTTL,
+		bcryptCost: cfg.Auth.BcryptCost,
+		mailer:     mail,
+		appURL:     cfg.Server.AppURL,
+
+		requireVerifiedLogin:    cfg.Auth.RequireVerifiedLogin,
+		requireVerifiedCheckout: cfg.Auth.RequireVerifiedCheckout,
+
+		storage:    files,
+		uploadsDir: uploadsDir,
+
+		readTimeout:     cfg.Server.ReadTimeout,
+		writeTimeout:    cfg.Server.WriteTimeout,
+		idleTimeout:     cfg.Server.IdleTimeout,
+		drainDelay:      cfg.Server.DrainDelay,
+		shutdownTimeout: cfg.Server.ShutdownTimeout,
+
+		metricsAddr: cfg.Server.MetricsAddr,
+
+		shutdownTracing: shutdownTracing,
 	}
 
 	api.Start()
 }
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/database"
+	"github.com/pressly/goose/v3"
+)
+
+// migrate runs `app migrate [up|down|status]` against the configured database
+// using the migrations embedded in the binary. up is the default; down rolls
+// back the latest migration only.
+func migrate(ctx context.Context, logger *slog.Logger, url string, command string) error {
+	// no statement_timeout: building indexes on a large table can take a while
+	db, err := database.NewPostgres(url, 2, 0, 0)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	var results []*goose.MigrationResult
+
+	switch command {
+	case "", "up":
+		results, err = migrator.Up(ctx)
+	case "down":
+		var result *goose.MigrationResult
+		result, err = migrator.Down(ctx)
+		if result != nil {
+			results = append(results, result)
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range statuses {
+			logger.Info("migration", "version", s.Source.Version, "file", s.Source.Path, "state", s.State)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q, want up, down or status", command)
+	}
+
+	for _, r := range results {
+		logger.Info("migration applied", "version", r.Source.Version, "file", r.Source.Path, "direction", r.Direction, "duration", r.Duration)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	version, err := migrator.GetDBVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("database migrated", "version", version, "applied", len(results))
+
+	return nil
+}
@@ -0,0 +1,420 @@
+// Package config loads the application settings. Each setting is resolved
+// from, in order of precedence: the process environment, a .env file in the
+// working directory, an optional YAML or TOML file and the built-in default.
+package config
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// Secret is a string that never shows up in logs: it prints as "[REDACTED]"
+// through fmt, %+v and %#v included.
+type Secret string
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+
+	return "[REDACTED]"
+}
+
+func (s Secret) GoString() string {
+	return `"` + s.String() + `"`
+}
+
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
+
+// Value returns the secret itself.
+func (s Secret) Value() string {
+	return string(s)
+}
+
+type Config struct {
+	Server   ServerConfig   `yaml:"server" toml:"server"`
+	Admin    AdminConfig    `yaml:"admin" toml:"admin"`
+	Database DatabaseConfig `yaml:"database" toml:"database"`
+	Redis    RedisConfig    `yaml:"redis" toml:"redis"`
+	Auth     AuthConfig     `yaml:"auth" toml:"auth"`
+	Cache    CacheConfig    `yaml:"cache" toml:"cache"`
+	Mail     MailConfig     `yaml:"mail" toml:"mail"`
+	Storage  StorageConfig  `yaml:"storage" toml:"storage"`
+	Tracing  TracingConfig  `yaml:"tracing" toml:"tracing"`
+	Log      LogConfig      `yaml:"log" toml:"log"`
+}
+
+type ServerConfig struct {
+	Addr            string        `yaml:"addr" toml:"addr" env:"API_ADDR" default:":7020"`
+	MetricsAddr     string        `yaml:"metrics_addr" toml:"metrics_addr" env:"METRICS_ADDR" default:":9090"`
+	AppURL          string        `yaml:"app_url" toml:"app_url" env:"APP_URL" required:"true"`
+	ReadTimeout     time.Duration `yaml:"read_timeout" toml:"read_timeout" env:"HTTP_READ_TIMEOUT" default:"30s"`
+	WriteTimeout    time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"HTTP_WRITE_TIMEOUT" default:"65s"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" default:"120s"`
+	DrainDelay      time.Duration `yaml:"drain_delay" toml:"drain_delay" env:"SHUTDOWN_DRAIN_DELAY" default:"5s"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" default:"30s"`
+}
+
+// AdminConfig is the account created on first start, when no admin exists.
+type AdminConfig struct {
+	Email    string `yaml:"email" toml:"email" env:"ADMIN_EMAIL"`
+	Password Secret `yaml:"password" toml:"password" env:"ADMIN_PASSWORD"`
+}
+
+type DatabaseConfig struct {
+	URL              Secret        `yaml:"url" toml:"url" env:"DATABASE_URL" required:"true"`
+	MaxConns         int           `yaml:"max_conns" toml:"max_conns" env:"DB_MAX_CONNS" default:"10"`
+	MinConns         int           `yaml:"min_conns" toml:"min_conns" env:"DB_MIN_CONNS" default:"2"`
+	StatementTimeout time.Duration `yaml:"statement_timeout" toml:"statement_timeout" env:"DB_STATEMENT_TIMEOUT" default:"10s"`
+}
+
+type RedisConfig struct {
+	Addr     string        `yaml:"addr" toml:"addr" env:"REDIS_URL" required:"true"`
+	Password Secret        `yaml:"password" toml:"password" env:"REDIS_PASSWORD"`
+	PoolSize int           `yaml:"pool_size" toml:"pool_size" env:"REDIS_POOL_SIZE" default:"20"`
+	Timeout  time.Duration `yaml:"timeout" toml:"timeout" env:"REDIS_TIMEOUT" default:"1s"`
+}
+
+type AuthConfig struct {
+	JWTSecret               Secret        `yaml:"jwt_secret" toml:"jwt_secret" env:"JWT_SECRET" required:"true"`
+	AccessTokenTTL          time.Duration `yaml:"access_token_ttl" toml:"access_token_ttl" env:"JWT_TTL" default:"5m"`
+	RefreshTokenTTL         time.Duration `yaml:"refresh_token_ttl" toml:"refresh_token_ttl" env:"REFRESH_TOKEN_TTL" default:"720h"`
+	BcryptCost              int           `yaml:"bcrypt_cost" toml:"bcrypt_cost" env:"BCRYPT_COST" default:"10"`
+	RequireVerifiedLogin    bool          `yaml:"require_verified_login" toml:"require_verified_login" env:"REQUIRE_VERIFIED_LOGIN"`
+	RequireVerifiedCheckout bool          `yaml:"require_verified_checkout" toml:"require_verified_checkout" env:"REQUIRE_VERIFIED_CHECKOUT"`
+}
+
+type CacheConfig struct {
+	TTL time.Duration `yaml:"ttl" toml:"ttl" env:"CACHE_TTL" default:"30m"`
+}
+
+// MailConfig selects how emails go out: "smtp" delivers them through SMTPHost,
+// "file" appends them to File and "log" prints them to stdout. The last two
+// expose reset and verification tokens, so they have to be asked for.
+type MailConfig struct {
+	Driver       string        `yaml:"driver" toml:"driver" env:"MAIL_DRIVER" default:"smtp"`
+	SMTPHost     string        `yaml:"smtp_host" toml:"smtp_host" env:"SMTP_HOST"`
+	SMTPPort     string        `yaml:"smtp_port" toml:"smtp_port" env:"SMTP_PORT" default:"587"`
+	SMTPUsername string        `yaml:"smtp_username" toml:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword Secret        `yaml:"smtp_password" toml:"smtp_password" env:"SMTP_PASSWORD"`
+	SMTPTimeout  time.Duration `yaml:"smtp_timeout" toml:"smtp_timeout" env:"SMTP_TIMEOUT" default:"10s"`
+	From         string        `yaml:"from" toml:"from" env:"SMTP_FROM" default:"no-reply@ecom.local"`
+	File         string        `yaml:"file" toml:"file" env:"MAIL_FILE"`
+}
+
+type StorageConfig struct {
+	Driver      string `yaml:"driver" toml:"driver" env:"STORAGE_DRIVER" default:"local"`
+	LocalDir    string `yaml:"local_dir" toml:"local_dir" env:"STORAGE_LOCAL_DIR" default:"./uploads"`
+	PublicURL   string `yaml:"public_url" toml:"public_url" env:"STORAGE_PUBLIC_URL"`
+	S3Endpoint  string `yaml:"s3_endpoint" toml:"s3_endpoint" env:"S3_ENDPOINT"`
+	S3AccessKey string `yaml:"s3_access_key" toml:"s3_access_key" env:"S3_ACCESS_KEY"`
+	S3SecretKey Secret `yaml:"s3_secret_key" toml:"s3_secret_key" env:"S3_SECRET_KEY"`
+	S3Bucket    string `yaml:"s3_bucket" toml:"s3_bucket" env:"S3_BUCKET"`
+	S3Region    string `yaml:"s3_region" toml:"s3_region" env:"S3_REGION" default:"us-east-1"`
+	S3UseSSL    bool   `yaml:"s3_use_ssl" toml:"s3_use_ssl" env:"S3_USE_SSL"`
+}
+
+// TracingConfig selects where spans go: "otlp" sends them to OTLPEndpoint
+// (or the standard OTEL_EXPORTER_OTLP_* variables when empty), "stdout" prints
+// them for local runs and "none" turns tracing off.
+type TracingConfig struct {
+	Exporter     string  `yaml:"exporter" toml:"exporter" env:"TRACING_EXPORTER" default:"none"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint" toml:"otlp_endpoint" env:"TRACING_OTLP_ENDPOINT"`
+	ServiceName  string  `yaml:"service_name" toml:"service_name" env:"OTEL_SERVICE_NAME" default:"ecom-api"`
+	SampleRatio  float64 `yaml:"sample_ratio" toml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" default:"1"`
+}
+
+type LogConfig struct {
+	Level slog.Level `yaml:"level" toml:"level" env:"LOG_LEVEL" default:"info"`
+}
+
+// Load builds the configuration. path is an optional .yaml, .yml or .toml
+// file; a missing .env file is not an error. Every problem found is reported
+// at once, so a bad deploy lists all missing variables in one go.
+func Load(path string) (Config, error) {
+	cfg, problems, err := read(path, nil)
+	if err != nil {
+		return cfg, err
+	}
+
+	problems = append(problems, cfg.validate()...)
+
+	return cfg, errors.Join(problems...)
+}
+
+// migrateSections are the only sections `app migrate` reads.
+var migrateSections = []string{"Database", "Log"}
+
+// LoadDatabase is Load for `app migrate`: only the database and log sections
+// are read and checked, so the migration job needs DATABASE_URL and none of
+// the API's secrets.
+func LoadDatabase(path string) (Config, error) {
+	cfg, problems, err := read(path, migrateSections)
+	if err != nil {
+		return cfg, err
+	}
+
+	var missing []string
+	walkSections(&cfg, migrateSections, func(f reflect.StructField, v reflect.Value) error {
+		if f.Tag.Get("required") == "true" && v.IsZero() {
+			missing = append(missing, f.Tag.Get("env"))
+		}
+		return nil
+	})
+	if len(missing) > 0 {
+		problems = append(problems, fmt.Errorf("missing required variables: %s", strings.Join(missing, ", ")))
+	}
+
+	return cfg, errors.Join(problems...)
+}
+
+// read applies defaults, the config file and the environment to the given
+// sections, or to every section when sections is nil. Bad values are returned
+// as problems; err is set when the sources themselves cannot be read.
+func read(path string, sections []string) (cfg Config, problems []error, err error) {
+	err = walkSections(&cfg, sections, func(f reflect.StructField, v reflect.Value) error {
+		if def, ok := f.Tag.Lookup("default"); ok {
+			return set(v, def)
+		}
+		return nil
+	})
+	if err != nil {
+		return cfg, nil, err
+	}
+
+	if path != "" {
+		if err := decodeFile(path, &cfg); err != nil {
+			return cfg, nil, err
+		}
+	}
+
+	// variables already in the environment win over .env
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil, fmt.Errorf("config: reading .env: %w", err)
+	}
+
+	walkSections(&cfg, sections, func(f reflect.StructField, v reflect.Value) error {
+		name := f.Tag.Get("env")
+		if raw, ok := os.LookupEnv(name); ok && raw != "" {
+			if err := set(v, raw); err != nil {
+				problems = append(problems, fmt.Errorf("%s: %w", name, err))
+			}
+		}
+		return nil
+	})
+
+	return cfg, problems, nil
+}
+
+func decodeFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	case ".toml":
+		err = toml.Unmarshal(data, cfg)
+	default:
+		return fmt.Errorf("config: unsupported file type %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func (c *Config) validate() []error {
+	var (
+		problems []error
+		missing  []string
+	)
+
+	walk(c, func(f reflect.StructField, v reflect.Value) error {
+		if f.Tag.Get("required") == "true" && v.IsZero() {
+			missing = append(missing, f.Tag.Get("env"))
+		}
+		return nil
+	})
+
+	if c.Storage.Driver == "s3" {
+		for _, s := range []struct{ name, value string }{
+			{"S3_ENDPOINT", c.Storage.S3Endpoint},
+			{"S3_ACCESS_KEY", c.Storage.S3AccessKey},
+			{"S3_SECRET_KEY", c.Storage.S3SecretKey.Value()},
+			{"S3_BUCKET", c.Storage.S3Bucket},
+		} {
+			if s.value == "" {
+				missing = append(missing, s.name)
+			}
+		}
+	}
+
+	switch c.Mail.Driver {
+	case "smtp":
+		if c.Mail.SMTPHost == "" {
+			missing = append(missing, "SMTP_HOST")
+		}
+	case "file":
+		if c.Mail.File == "" {
+			missing = append(missing, "MAIL_FILE")
+		}
+	}
+
+	if len(missing) > 0 {
+		problems = append(problems, fmt.Errorf("missing required variables: %s", strings.Join(missing, ", ")))
+	}
+
+	if c.Storage.Driver != "local" && c.Storage.Driver != "s3" {
+		problems = append(problems, fmt.Errorf("STORAGE_DRIVER: must be local or s3, got %q", c.Storage.Driver))
+	}
+	if c.Mail.Driver != "smtp" && c.Mail.Driver != "file" && c.Mail.Driver != "log" {
+		problems = append(problems, fmt.Errorf("MAIL_DRIVER: must be smtp, file or log, got %q", c.Mail.Driver))
+	}
+	if c.Mail.SMTPTimeout <= 0 {
+		problems = append(problems, errors.New("SMTP_TIMEOUT must be positive"))
+	}
+	if c.Auth.BcryptCost < bcrypt.MinCost || c.Auth.BcryptCost > bcrypt.MaxCost {
+		problems = append(problems, fmt.Errorf("BCRYPT_COST: must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost))
+	}
+	if c.Auth.AccessTokenTTL <= 0 || c.Auth.RefreshTokenTTL <= 0 || c.Cache.TTL <= 0 {
+		problems = append(problems, errors.New("JWT_TTL, REFRESH_TOKEN_TTL and CACHE_TTL must be positive"))
+	}
+	if c.Database.MaxConns < 1 || c.Database.MinConns < 0 || c.Database.MinConns > c.Database.MaxConns {
+		problems = append(problems, errors.New("DB_MAX_CONNS must be at least 1 and DB_MIN_CONNS between 0 and DB_MAX_CONNS"))
+	}
+	switch c.Tracing.Exporter {
+	case "otlp", "stdout", "none":
+	default:
+		problems = append(problems, fmt.Errorf("TRACING_EXPORTER: must be otlp, stdout or none, got %q", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems = append(problems, errors.New("TRACING_SAMPLE_RATIO must be between 0 and 1"))
+	}
+	if c.Database.StatementTimeout <= 0 || c.Redis.Timeout <= 0 {
+		problems = append(problems, errors.New("DB_STATEMENT_TIMEOUT and REDIS_TIMEOUT must be positive"))
+	}
+	if c.Redis.PoolSize < 1 {
+		problems = append(problems, errors.New("REDIS_POOL_SIZE must be at least 1"))
+	}
+
+	return problems
+}
+
+// String lists every setting by its variable name, with secrets redacted.
+func (c Config) String() string {
+	var b strings.Builder
+
+	walk(&c, func(f reflect.StructField, v reflect.Value) error {
+		fmt.Fprintf(&b, "%s=%v\n", f.Tag.Get("env"), v.Interface())
+		return nil
+	})
+
+	return b.String()
+}
+
+// LogValue logs the settings as a group keyed by variable name, with secrets
+// redacted.
+func (c Config) LogValue() slog.Value {
+	var attrs []slog.Attr
+
+	walk(&c, func(f reflect.StructField, v reflect.Value) error {
+		attrs = append(attrs, slog.String(f.Tag.Get("env"), fmt.Sprint(v.Interface())))
+		return nil
+	})
+
+	return slog.GroupValue(attrs...)
+}
+
+// walk calls fn for every leaf setting, i.e. every field with an env tag.
+func walk(cfg *Config, fn func(reflect.StructField, reflect.Value) error) error {
+	return walkSections(cfg, nil, fn)
+}
+
+// walkSections is walk limited to the named Config fields; nil means all.
+func walkSections(cfg *Config, names []string, fn func(reflect.StructField, reflect.Value) error) error {
+	var errs []error
+
+	sections := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < sections.NumField(); i++ {
+		if names != nil && !slices.Contains(names, sections.Type().Field(i).Name) {
+			continue
+		}
+
+		section := sections.Field(i)
+		for j := 0; j < section.NumField(); j++ {
+			f := section.Type().Field(j)
+			if _, ok := f.Tag.Lookup("env"); !ok {
+				continue
+			}
+
+			if err := fn(f, section.Field(j)); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", f.Tag.Get("env"), err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func set(v reflect.Value, raw string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(raw))
+	}
+
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// isolate runs the test in an empty directory, so no .env is picked up, and
+// blanks every setting's variable, which Load treats as unset.
+func isolate(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	walk(&Config{}, func(f reflect.StructField, v reflect.Value) error {
+		t.Setenv(f.Tag.Get("env"), "")
+		return nil
+	})
+
+	return dir
+}
+
+// setRequired sets the minimum a full Load accepts.
+func setRequired(t *testing.T) {
+	t.Helper()
+
+	for name, value := range map[string]string{
+		"APP_URL":      "http://localhost:3000",
+		"DATABASE_URL": "postgres://localhost:5432/ecom",
+		"REDIS_URL":    "localhost:6379",
+		"JWT_SECRET":   "test-secret",
+		"MAIL_DRIVER":  "log",
+	} {
+		t.Setenv(name, value)
+	}
+}
+
+func writeFile(t *testing.T, dir string, name string, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		contents string
+		dotenv   string
+		env      string
+		want     time.Duration
+	}{
+		{name: "default", want: 30 * time.Minute},
+		{name: "yaml", file: "config.yaml", contents: "cache:\n  ttl: 1m\n", want: time.Minute},
+		{name: "toml", file: "config.toml", contents: "[cache]\nttl = \"2m\"\n", want: 2 * time.Minute},
+		{name: ".env over file", file: "config.yaml", contents: "cache:\n  ttl: 1m\n", dotenv: "CACHE_TTL=3m\n", want: 3 * time.Minute},
+		{name: "env over .env", file: "config.yaml", contents: "cache:\n  ttl: 1m\n", dotenv: "CACHE_TTL=3m\n", env: "4m", want: 4 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolate(t)
+			setRequired(t)
+
+			var path string
+			if tt.file != "" {
+				path = writeFile(t, dir, tt.file, tt.contents)
+			}
+			if tt.dotenv != "" {
+				writeFile(t, dir, ".env", tt.dotenv)
+				// godotenv only fills variables that are absent, not blank
+				os.Unsetenv("CACHE_TTL")
+			}
+			if tt.env != "" {
+				t.Setenv("CACHE_TTL", tt.env)
+			}
+
+			cfg, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Cache.TTL != tt.want {
+				t.Errorf("CACHE_TTL = %v, want %v", cfg.Cache.TTL, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want []string
+	}{
+		{name: "valid"},
+		{name: "missing required", env: map[string]string{"JWT_SECRET": "", "REDIS_URL": ""}, want: []string{"missing required variables", "REDIS_URL", "JWT_SECRET"}},
+		{name: "smtp without host", env: map[string]string{"MAIL_DRIVER": "smtp"}, want: []string{"SMTP_HOST"}},
+		{name: "smtp by default", env: map[string]string{"MAIL_DRIVER": ""}, want: []string{"SMTP_HOST"}},
+		{name: "smtp with host", env: map[string]string{"MAIL_DRIVER": "smtp", "SMTP_HOST": "smtp.example.com"}},
+		{name: "file without path", env: map[string]string{"MAIL_DRIVER": "file"}, want: []string{"MAIL_FILE"}},
+		{name: "unknown mail driver", env: map[string]string{"MAIL_DRIVER": "pigeon"}, want: []string{"MAIL_DRIVER: must be smtp, file or log"}},
+		{name: "s3 without credentials", env: map[string]string{"STORAGE_DRIVER": "s3"}, want: []string{"S3_ENDPOINT", "S3_ACCESS_KEY", "S3_SECRET_KEY", "S3_BUCKET"}},
+		{name: "unparsable value", env: map[string]string{"CACHE_TTL": "soon"}, want: []string{"CACHE_TTL"}},
+		{name: "problems reported together", env: map[string]string{"JWT_SECRET": "", "BCRYPT_COST": "99"}, want: []string{"JWT_SECRET", "BCRYPT_COST"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolate(t)
+			setRequired(t)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			_, err := Load("")
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("got %v, want no error", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("got no error, want one mentioning %v", tt.want)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("error %q does not mention %q", err, w)
+				}
+			}
+		})
+	}
+}
+
+func TestSecretRedaction(t *testing.T) {
+	isolate(t)
+	setRequired(t)
+	t.Setenv("SMTP_PASSWORD", "smtp-password")
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Auth.JWTSecret.Value() != "test-secret" {
+		t.Fatalf("JWT_SECRET = %q, want the configured value", cfg.Auth.JWTSecret.Value())
+	}
+
+	var logged bytes.Buffer
+	slog.New(slog.NewJSONHandler(&logged, nil)).Info("configuration loaded", "config", cfg)
+
+	outputs := map[string]string{
+		"String":  cfg.String(),
+		"%v":      fmt.Sprintf("%v", cfg),
+		"%#v":     fmt.Sprintf("%#v", cfg),
+		"slog":    logged.String(),
+		"%s leaf": fmt.Sprintf("%s", cfg.Database.URL),
+	}
+	for name, out := range outputs {
+		for _, secret := range []string{"test-secret", "smtp-password", "postgres://localhost:5432/ecom"} {
+			if strings.Contains(out, secret) {
+				t.Errorf("%s output leaks %q: %s", name, secret, out)
+			}
+		}
+	}
+
+	if !strings.Contains(cfg.String(), "JWT_SECRET=[REDACTED]") {
+		t.Errorf("String() = %q, want JWT_SECRET redacted", cfg.String())
+	}
+}
+
+func TestLoadDatabase(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{name: "database url alone", env: map[string]string{"DATABASE_URL": "postgres://localhost:5432/ecom"}},
+		{
+			name: "other sections ignored",
+			env: map[string]string{
+				"DATABASE_URL":   "postgres://localhost:5432/ecom",
+				"MAIL_DRIVER":    "pigeon",
+				"STORAGE_DRIVER": "s3",
+				"CACHE_TTL":      "soon",
+				"BCRYPT_COST":    "99",
+			},
+		},
+		{name: "missing database url", env: map[string]string{"JWT_SECRET": "test-secret"}, wantErr: "DATABASE_URL"},
+		{name: "bad log level", env: map[string]string{"DATABASE_URL": "postgres://localhost:5432/ecom", "LOG_LEVEL": "loud"}, wantErr: "LOG_LEVEL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolate(t)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			cfg, err := LoadDatabase("")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("got %v, want no error", err)
+				}
+				if cfg.Database.URL.Value() != tt.env["DATABASE_URL"] {
+					t.Errorf("DATABASE_URL = %q, want %q", cfg.Database.URL.Value(), tt.env["DATABASE_URL"])
+				}
+				if cfg.Cache.TTL != 0 || cfg.Mail.Driver != "" {
+					t.Errorf("unrelated sections were loaded: %+v %+v", cfg.Cache, cfg.Mail)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want an error mentioning %s", err, tt.wantErr)
+			}
+		})
+	}
+}
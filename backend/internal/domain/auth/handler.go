@@ -3,10 +3,12 @@ package auth
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 
 	"github.com/euandresimoes/ecom-go/backend/internal/infra/security"
 	"github.com/euandresimoes/ecom-go/backend/internal/middlewares"
 	"github.com/euandresimoes/ecom-go/backend/internal/models"
+	"github.com/euandresimoes/ecom-go/backend/internal/response"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
 )
@@ -28,10 +30,19 @@ func NewHandler(service *Service, validator *validator.Validate, jwtManager *sec
 
 	r.Post("/register", h.Register)
 	r.Post("/login", h.Login)
+	r.Post("/refresh", h.Refresh)
+	r.Post("/logout", h.Logout)
+	r.Post("/password/forgot", h.ForgotPassword)
+	r.Post("/password/reset", h.ResetPassword)
+	r.Get("/verify", h.VerifyEmail)
+	r.Post("/verify/resend", h.ResendVerification)
 
 	r.Group(func(protected chi.Router) {
 		protected.Use(middlewares.Auth(h.jwtManager))
 		protected.Get("/profile", h.Profile)
+		protected.Patch("/profile", h.UpdateProfile)
+		protected.Delete("/profile", h.DeleteProfile)
+		protected.Post("/logout/all", h.LogoutAll)
 	})
 
 	return r
@@ -42,34 +53,23 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 
 	err := json.NewDecoder(r.Body).Decode(&data)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]any{
-			"status": http.StatusBadRequest,
-			"error":  "invalid json",
-		})
+		response.Problem(w, r, http.StatusBadRequest, "invalid json")
 		return
 	}
 
 	err = h.validator.Struct(data)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]any{
-			"status": http.StatusBadRequest,
-			"error":  err.Error(),
-		})
+		response.Error(w, r, err)
 		return
 	}
 
-	err = h.service.Register(data)
+	err = h.service.Register(r.Context(), data)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]any{
-			"status": http.StatusBadRequest,
-			"error":  err.Error(),
-		})
+		response.Error(w, r, err)
 		return
 	}
 
+	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]any{
 		"status":  http.StatusCreated,
 		"message": "account created successfully",
@@ -81,53 +81,92 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 
 	err := json.NewDecoder(r.Body).Decode(&data)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]any{
-			"status": http.StatusBadRequest,
-			"error":  "invalid json",
-		})
+		response.Problem(w, r, http.StatusBadRequest, "invalid json")
 		return
 	}
 
 	err = h.validator.Struct(data)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]any{
-			"status": http.StatusBadRequest,
-			"error":  err.Error(),
-		})
+		response.Error(w, r, err)
 		return
 	}
 
-	token, err := h.service.Login(data)
+	tokens, err := h.service.Login(r.Context(), data)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]any{
-			"status": http.StatusBadRequest,
-			"error":  err.Error(),
-		})
+		response.Error(w, r, err)
 		return
 	}
 
 	json.NewEncoder(w).Encode(map[string]any{
 		"status":  http.StatusOK,
 		"message": "login success",
-		"data": map[string]any{
-			"token": token,
-		},
+		"data":    tokens,
+	})
+}
+
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var data models.UserRefreshModel
+
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	err = h.validator.Struct(data)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	tokens, err := h.service.Refresh(r.Context(), data)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "token refreshed",
+		"data":    tokens,
+	})
+}
+
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	var data models.UserRefreshModel
+
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	err = h.validator.Struct(data)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	accessToken := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	err = h.service.Logout(r.Context(), data, accessToken)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "logout success",
 	})
 }
 
 func (h *Handler) Profile(w http.ResponseWriter, r *http.Request) {
 	id := r.Context().Value(models.UserIDKey).(float64)
 
-	profile, err := h.service.Profile(id)
+	profile, err := h.service.Profile(r.Context(), int(id))
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]any{
-			"status": http.StatusBadRequest,
-			"error":  err.Error(),
-		})
+		response.Error(w, r, err)
 		return
 	}
 
@@ -137,3 +176,173 @@ func (h *Handler) Profile(w http.ResponseWriter, r *http.Request) {
 		"data":    profile,
 	})
 }
+
+func (h *Handler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value(models.UserIDKey).(float64)
+
+	err := h.service.RevokeSessions(r.Context(), int(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "all sessions revoked",
+	})
+}
+
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var data models.UserForgotPasswordModel
+
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	err = h.validator.Struct(data)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = h.service.ForgotPassword(r.Context(), data)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "if the email is registered, a reset link has been sent",
+	})
+}
+
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var data models.UserResetPasswordModel
+
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	err = h.validator.Struct(data)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = h.service.ResetPassword(r.Context(), data)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "password reset successfully",
+	})
+}
+
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+
+	err := h.service.VerifyEmail(r.Context(), token)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "email verified",
+	})
+}
+
+func (h *Handler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var data models.UserResendVerificationModel
+
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	err = h.validator.Struct(data)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = h.service.ResendVerification(r.Context(), data)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "if the email is registered and unverified, a verification link has been sent",
+	})
+}
+
+func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value(models.UserIDKey).(float64)
+
+	var data models.UserUpdateModel
+
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	err = h.validator.Struct(data)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	profile, err := h.service.UpdateProfile(r.Context(), int(id), data)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "profile updated",
+		"data":    profile,
+	})
+}
+
+func (h *Handler) DeleteProfile(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value(models.UserIDKey).(float64)
+
+	var data models.UserDeleteModel
+
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	err = h.validator.Struct(data)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = h.service.DeleteProfile(r.Context(), int(id), data)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "account deleted",
+	})
+}
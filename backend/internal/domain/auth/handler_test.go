@@ -0,0 +1,370 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/mailer"
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/security"
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/validation"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type testServer struct {
+	handler http.Handler
+	mail    *bytes.Buffer
+}
+
+func newTestServer(t *testing.T, requireVerifiedLogin bool) *testServer {
+	t.Helper()
+
+	jwt := security.NewJWTManager("test-secret", 15*time.Minute, nil)
+	repo := NewMemoryRepository(jwt, time.Hour, requireVerifiedLogin, bcrypt.MinCost)
+	mail := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	service := NewService(repo, mailer.NewLog(mail), "http://localhost", logger)
+
+	return &testServer{
+		handler: NewHandler(service, validation.Validator(), jwt),
+		mail:    mail,
+	}
+}
+
+type testResponse struct {
+	Status  int             `json:"status"`
+	Message string          `json:"message"`
+	Detail  string          `json:"detail"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func (s *testServer) do(t *testing.T, method string, path string, token string, body any) (int, testResponse) {
+	t.Helper()
+
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = bytes.NewBufferString(b)
+	default:
+		raw, err := json.Marshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, req)
+
+	var res testResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("%s %s: decoding %q: %v", method, path, rec.Body.String(), err)
+	}
+
+	return rec.Code, res
+}
+
+func (s *testServer) register(t *testing.T, email string, password string) {
+	t.Helper()
+
+	status, res := s.do(t, http.MethodPost, "/register", "", map[string]string{
+		"first_name": "Alice",
+		"last_name":  "Smith",
+		"email":      email,
+		"password":   password,
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("register: got %d %q", status, res.Detail)
+	}
+}
+
+func (s *testServer) login(t *testing.T, email string, password string) (string, string) {
+	t.Helper()
+
+	status, res := s.do(t, http.MethodPost, "/login", "", map[string]string{
+		"email":    email,
+		"password": password,
+	})
+	if status != http.StatusOK {
+		t.Fatalf("login: got %d %q", status, res.Detail)
+	}
+
+	var tokens struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.Unmarshal(res.Data, &tokens); err != nil {
+		t.Fatal(err)
+	}
+
+	return tokens.Token, tokens.RefreshToken
+}
+
+var verifyLink = regexp.MustCompile(`/verify\?token=(\S+)`)
+
+func (s *testServer) verificationToken(t *testing.T) string {
+	t.Helper()
+
+	m := verifyLink.FindAllStringSubmatch(s.mail.String(), -1)
+	if len(m) == 0 {
+		t.Fatalf("no verification link in %q", s.mail.String())
+	}
+
+	return m[len(m)-1][1]
+}
+
+func TestRegister(t *testing.T) {
+	s := newTestServer(t, false)
+	s.register(t, "alice@example.com", "password123")
+
+	if !verifyLink.MatchString(s.mail.String()) {
+		t.Error("register did not send a verification link")
+	}
+
+	tests := []struct {
+		name   string
+		body   any
+		status int
+	}{
+		{"duplicate email", map[string]string{"first_name": "Alice", "email": "alice@example.com", "password": "password123"}, http.StatusConflict},
+		{"invalid json", "{", http.StatusBadRequest},
+		{"missing fields", map[string]string{"email": "bob@example.com"}, http.StatusUnprocessableEntity},
+		{"invalid email", map[string]string{"first_name": "Bob", "email": "bob", "password": "password123"}, http.StatusUnprocessableEntity},
+		{"short password", map[string]string{"first_name": "Bob", "email": "bob@example.com", "password": "short"}, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, res := s.do(t, http.MethodPost, "/register", "", tt.body)
+			if status != tt.status {
+				t.Errorf("got %d %q, want %d", status, res.Detail, tt.status)
+			}
+		})
+	}
+}
+
+func TestLogin(t *testing.T) {
+	s := newTestServer(t, false)
+	s.register(t, "alice@example.com", "password123")
+
+	token, refresh := s.login(t, "alice@example.com", "password123")
+	if token == "" || refresh == "" {
+		t.Fatal("login returned an empty token pair")
+	}
+
+	tests := []struct {
+		name   string
+		body   any
+		status int
+	}{
+		{"wrong password", map[string]string{"email": "alice@example.com", "password": "wrongpassword"}, http.StatusUnauthorized},
+		{"unknown account", map[string]string{"email": "bob@example.com", "password": "password123"}, http.StatusNotFound},
+		{"invalid json", "not json", http.StatusBadRequest},
+		{"missing password", map[string]string{"email": "alice@example.com"}, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, res := s.do(t, http.MethodPost, "/login", "", tt.body)
+			if status != tt.status {
+				t.Errorf("got %d %q, want %d", status, res.Detail, tt.status)
+			}
+		})
+	}
+}
+
+func TestLoginRequiresVerifiedEmail(t *testing.T) {
+	s := newTestServer(t, true)
+	s.register(t, "alice@example.com", "password123")
+
+	body := map[string]string{"email": "alice@example.com", "password": "password123"}
+	if status, _ := s.do(t, http.MethodPost, "/login", "", body); status != http.StatusForbidden {
+		t.Fatalf("unverified login: got %d, want %d", status, http.StatusForbidden)
+	}
+
+	if status, _ := s.do(t, http.MethodGet, "/verify?token=garbage", "", nil); status != http.StatusUnprocessableEntity {
+		t.Errorf("bad verification token: got %d, want %d", status, http.StatusUnprocessableEntity)
+	}
+
+	if status, res := s.do(t, http.MethodGet, "/verify?token="+s.verificationToken(t), "", nil); status != http.StatusOK {
+		t.Fatalf("verify: got %d %q", status, res.Detail)
+	}
+
+	s.login(t, "alice@example.com", "password123")
+}
+
+func TestRefresh(t *testing.T) {
+	s := newTestServer(t, false)
+	s.register(t, "alice@example.com", "password123")
+	_, refresh := s.login(t, "alice@example.com", "password123")
+
+	status, res := s.do(t, http.MethodPost, "/refresh", "", map[string]string{"refresh_token": refresh})
+	if status != http.StatusOK {
+		t.Fatalf("refresh: got %d %q", status, res.Detail)
+	}
+
+	var rotated struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	json.Unmarshal(res.Data, &rotated)
+
+	// presenting the old token again revokes the whole family
+	if status, _ := s.do(t, http.MethodPost, "/refresh", "", map[string]string{"refresh_token": refresh}); status != http.StatusUnauthorized {
+		t.Errorf("reused token: got %d, want %d", status, http.StatusUnauthorized)
+	}
+	if status, _ := s.do(t, http.MethodPost, "/refresh", "", map[string]string{"refresh_token": rotated.RefreshToken}); status != http.StatusUnauthorized {
+		t.Errorf("token from a revoked family: got %d, want %d", status, http.StatusUnauthorized)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	s := newTestServer(t, false)
+	s.register(t, "alice@example.com", "password123")
+	token, _ := s.login(t, "alice@example.com", "password123")
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		if status, _ := s.do(t, http.MethodGet, "/profile", "", nil); status != http.StatusUnauthorized {
+			t.Errorf("missing token: got %d, want %d", status, http.StatusUnauthorized)
+		}
+		if status, _ := s.do(t, http.MethodGet, "/profile", "not-a-jwt", nil); status != http.StatusUnauthorized {
+			t.Errorf("invalid token: got %d, want %d", status, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("get", func(t *testing.T) {
+		status, res := s.do(t, http.MethodGet, "/profile", token, nil)
+		if status != http.StatusOK {
+			t.Fatalf("got %d %q", status, res.Detail)
+		}
+
+		var profile struct {
+			FirstName string `json:"first_name"`
+			Email     string `json:"email"`
+		}
+		json.Unmarshal(res.Data, &profile)
+		if profile.FirstName != "Alice" || profile.Email != "alice@example.com" {
+			t.Errorf("got profile %+v", profile)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		status, res := s.do(t, http.MethodPatch, "/profile", token, map[string]string{"first_name": "Alicia"})
+		if status != http.StatusOK {
+			t.Fatalf("got %d %q", status, res.Detail)
+		}
+
+		var profile struct {
+			FirstName string `json:"first_name"`
+		}
+		json.Unmarshal(res.Data, &profile)
+		if profile.FirstName != "Alicia" {
+			t.Errorf("first_name = %q, want %q", profile.FirstName, "Alicia")
+		}
+	})
+
+	t.Run("update errors", func(t *testing.T) {
+		s.register(t, "bob@example.com", "password123")
+
+		tests := []struct {
+			name   string
+			body   any
+			status int
+		}{
+			{"email without password", map[string]string{"email": "new@example.com"}, http.StatusUnprocessableEntity},
+			{"wrong current password", map[string]string{"email": "new@example.com", "current_password": "wrongpassword"}, http.StatusForbidden},
+			{"email taken", map[string]string{"email": "bob@example.com", "current_password": "password123"}, http.StatusConflict},
+			{"invalid json", "{", http.StatusBadRequest},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				status, res := s.do(t, http.MethodPatch, "/profile", token, tt.body)
+				if status != tt.status {
+					t.Errorf("got %d %q, want %d", status, res.Detail, tt.status)
+				}
+			})
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		body := map[string]string{"password": "wrongpassword"}
+		if status, _ := s.do(t, http.MethodDelete, "/profile", token, body); status != http.StatusForbidden {
+			t.Errorf("wrong password: got %d, want %d", status, http.StatusForbidden)
+		}
+
+		body = map[string]string{"password": "password123"}
+		if status, res := s.do(t, http.MethodDelete, "/profile", token, body); status != http.StatusOK {
+			t.Fatalf("got %d %q", status, res.Detail)
+		}
+
+		login := map[string]string{"email": "alice@example.com", "password": "password123"}
+		if status, _ := s.do(t, http.MethodPost, "/login", "", login); status != http.StatusNotFound {
+			t.Errorf("login after delete: got %d, want %d", status, http.StatusNotFound)
+		}
+	})
+}
+
+type failingMailer struct{}
+
+func (failingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	return errors.New("smtp: connection refused")
+}
+
+// a mail failure happens only for registered addresses, so it must not change
+// the answer
+func TestForgotPasswordMailFailure(t *testing.T) {
+	jwt := security.NewJWTManager("test-secret", 15*time.Minute, nil)
+	repo := NewMemoryRepository(jwt, time.Hour, false, bcrypt.MinCost)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := &testServer{handler: NewHandler(NewService(repo, failingMailer{}, "http://localhost", logger), validation.Validator(), jwt)}
+	s.register(t, "alice@example.com", "password123")
+
+	for _, email := range []string{"alice@example.com", "nobody@example.com"} {
+		if status, _ := s.do(t, http.MethodPost, "/password/forgot", "", map[string]string{"email": email}); status != http.StatusOK {
+			t.Errorf("forgot %s: got %d, want %d", email, status, http.StatusOK)
+		}
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	s := newTestServer(t, false)
+	s.register(t, "alice@example.com", "password123")
+
+	// unknown addresses get the same answer as known ones
+	for _, email := range []string{"alice@example.com", "nobody@example.com"} {
+		if status, _ := s.do(t, http.MethodPost, "/password/forgot", "", map[string]string{"email": email}); status != http.StatusOK {
+			t.Errorf("forgot %s: got %d, want %d", email, status, http.StatusOK)
+		}
+	}
+
+	m := regexp.MustCompile(`reset-password\?token=(\S+)`).FindStringSubmatch(s.mail.String())
+	if m == nil {
+		t.Fatalf("no reset link in %q", s.mail.String())
+	}
+	token, _ := url.QueryUnescape(m[1])
+
+	body := map[string]string{"token": token, "password": "newpassword123"}
+	if status, res := s.do(t, http.MethodPost, "/password/reset", "", body); status != http.StatusOK {
+		t.Fatalf("reset: got %d %q", status, res.Detail)
+	}
+	if status, _ := s.do(t, http.MethodPost, "/password/reset", "", body); status != http.StatusUnprocessableEntity {
+		t.Errorf("reused reset token: got %d, want %d", status, http.StatusUnprocessableEntity)
+	}
+
+	s.login(t, "alice@example.com", "newpassword123")
+}
@@ -0,0 +1,451 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/security"
+	"github.com/euandresimoes/ecom-go/backend/internal/models"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lucsky/cuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MemoryRepository is a Repository kept in process memory. It follows the
+// rules PostgresRepository enforces, so handlers and services can be tested
+// without Postgres or Redis.
+type MemoryRepository struct {
+	mu sync.Mutex
+
+	jwtManager           *security.JWTManager
+	refreshExp           time.Duration
+	requireVerifiedLogin bool
+	bcryptCost           int
+
+	users         map[int]*memoryUser
+	refreshTokens map[string]*memoryRefreshToken
+	resetTokens   map[string]*memoryResetToken
+	nextID        int
+}
+
+type memoryUser struct {
+	id              int
+	firstName       string
+	lastName        string
+	email           string
+	passwordHash    string
+	role            models.UserRole
+	emailVerifiedAt pgtype.Timestamptz
+	disabled        bool
+}
+
+type memoryRefreshToken struct {
+	userID    int
+	familyID  string
+	expiresAt time.Time
+	revoked   bool
+}
+
+type memoryResetToken struct {
+	userID    int
+	expiresAt time.Time
+	used      bool
+}
+
+func NewMemoryRepository(jwtManager *security.JWTManager, refreshExp time.Duration, requireVerifiedLogin bool, bcryptCost int) *MemoryRepository {
+	return &MemoryRepository{
+		jwtManager:           jwtManager,
+		refreshExp:           refreshExp,
+		requireVerifiedLogin: requireVerifiedLogin,
+		bcryptCost:           bcryptCost,
+		users:                map[int]*memoryUser{},
+		refreshTokens:        map[string]*memoryRefreshToken{},
+		resetTokens:          map[string]*memoryResetToken{},
+		nextID:               1,
+	}
+}
+
+func (r *MemoryRepository) byEmail(email string) *memoryUser {
+	for _, u := range r.users {
+		if u.email == email {
+			return u
+		}
+	}
+
+	return nil
+}
+
+func (r *MemoryRepository) Register(ctx context.Context, data models.UserRegisterModel) (int, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(data.Password), r.bcryptCost)
+	if err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.byEmail(data.Email) != nil {
+		return 0, ErrEmailInUse
+	}
+
+	u := &memoryUser{
+		id:           r.nextID,
+		firstName:    data.FirstName,
+		lastName:     data.LastName,
+		email:        data.Email,
+		passwordHash: string(hashedPassword),
+		role:         models.RoleCustomer,
+	}
+	r.users[u.id] = u
+	r.nextID++
+
+	return u.id, nil
+}
+
+func (r *MemoryRepository) Login(ctx context.Context, data models.UserLoginModel) (models.TokenPairModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u := r.byEmail(data.Email)
+	if u == nil {
+		return models.TokenPairModel{}, ErrAccountNotFound
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(u.passwordHash), []byte(data.Password)) != nil {
+		return models.TokenPairModel{}, ErrInvalidCredentials
+	}
+
+	if u.disabled {
+		return models.TokenPairModel{}, ErrAccountDisabled
+	}
+
+	if r.requireVerifiedLogin && !u.emailVerifiedAt.Valid {
+		return models.TokenPairModel{}, ErrEmailNotVerified
+	}
+
+	return r.issueTokens(u, cuid.New())
+}
+
+// issueTokens must be called with mu held.
+func (r *MemoryRepository) issueTokens(u *memoryUser, familyID string) (models.TokenPairModel, error) {
+	var pair models.TokenPairModel
+
+	refreshToken, err := security.NewOpaqueToken()
+	if err != nil {
+		return pair, err
+	}
+
+	r.refreshTokens[security.HashToken(refreshToken)] = &memoryRefreshToken{
+		userID:    u.id,
+		familyID:  familyID,
+		expiresAt: time.Now().Add(r.refreshExp),
+	}
+
+	accessToken, err := r.jwtManager.Sign(u.id, u.role)
+	if err != nil {
+		return pair, err
+	}
+
+	pair.AccessToken = accessToken
+	pair.RefreshToken = refreshToken
+
+	return pair, nil
+}
+
+func (r *MemoryRepository) Refresh(ctx context.Context, data models.UserRefreshModel) (models.TokenPairModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.refreshTokens[security.HashToken(data.RefreshToken)]
+	if !ok {
+		return models.TokenPairModel{}, ErrInvalidRefreshToken
+	}
+
+	if t.revoked {
+		r.revokeFamily(t.familyID)
+		return models.TokenPairModel{}, ErrRefreshTokenReused
+	}
+
+	if time.Now().After(t.expiresAt) {
+		return models.TokenPairModel{}, ErrRefreshTokenExpired
+	}
+
+	u := r.users[t.userID]
+	if u.disabled {
+		return models.TokenPairModel{}, ErrAccountDisabled
+	}
+
+	t.revoked = true
+
+	return r.issueTokens(u, t.familyID)
+}
+
+// revokeFamily must be called with mu held. It reports how many tokens were
+// still live.
+func (r *MemoryRepository) revokeFamily(familyID string) int {
+	n := 0
+	for _, t := range r.refreshTokens {
+		if t.familyID == familyID && !t.revoked {
+			t.revoked = true
+			n++
+		}
+	}
+
+	return n
+}
+
+func (r *MemoryRepository) Logout(ctx context.Context, data models.UserRefreshModel, accessToken string) error {
+	r.mu.Lock()
+	t, ok := r.refreshTokens[security.HashToken(data.RefreshToken)]
+	revoked := 0
+	if ok {
+		revoked = r.revokeFamily(t.familyID)
+	}
+	r.mu.Unlock()
+
+	if revoked == 0 {
+		return ErrInvalidRefreshToken
+	}
+
+	if accessToken == "" {
+		return nil
+	}
+
+	token, err := r.jwtManager.Verify(accessToken)
+	if err != nil {
+		return nil
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil
+	}
+
+	return r.jwtManager.Revoke(ctx, claims)
+}
+
+func (r *MemoryRepository) RevokeSessions(ctx context.Context, id int) error {
+	r.mu.Lock()
+	for _, t := range r.refreshTokens {
+		if t.userID == id {
+			t.revoked = true
+		}
+	}
+	r.mu.Unlock()
+
+	return r.jwtManager.RevokeUser(ctx, id)
+}
+
+func (r *MemoryRepository) Profile(ctx context.Context, id int) (models.UserPublicModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return models.UserPublicModel{}, ErrAccountNotFound
+	}
+
+	return u.public(), nil
+}
+
+func (u *memoryUser) public() models.UserPublicModel {
+	return models.UserPublicModel{
+		FirstName:       u.firstName,
+		LastName:        u.lastName,
+		Email:           u.email,
+		EmailVerifiedAt: u.emailVerifiedAt,
+	}
+}
+
+func (r *MemoryRepository) CreateResetToken(ctx context.Context, email string, ttl time.Duration) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u := r.byEmail(email)
+	if u == nil {
+		return "", nil
+	}
+
+	token, err := security.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	r.resetTokens[security.HashToken(token)] = &memoryResetToken{
+		userID:    u.id,
+		expiresAt: time.Now().Add(ttl),
+	}
+
+	return token, nil
+}
+
+func (r *MemoryRepository) ResetPassword(ctx context.Context, data models.UserResetPasswordModel) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(data.Password), r.bcryptCost)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	t, ok := r.resetTokens[security.HashToken(data.Token)]
+	if !ok || t.used || time.Now().After(t.expiresAt) {
+		r.mu.Unlock()
+		return ErrInvalidResetToken
+	}
+
+	r.users[t.userID].passwordHash = string(hashedPassword)
+	for _, other := range r.resetTokens {
+		if other.userID == t.userID {
+			other.used = true
+		}
+	}
+	r.mu.Unlock()
+
+	return r.RevokeSessions(ctx, t.userID)
+}
+
+func (r *MemoryRepository) EmailVerificationToken(ctx context.Context, id int, email string, expires time.Duration) (string, error) {
+	return r.jwtManager.SignEmailVerification(id, email, expires)
+}
+
+func (r *MemoryRepository) VerificationTarget(ctx context.Context, email string) (int, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u := r.byEmail(email)
+	if u == nil {
+		return 0, false, ErrAccountNotFound
+	}
+
+	return u.id, u.emailVerifiedAt.Valid, nil
+}
+
+func (r *MemoryRepository) VerifyEmail(ctx context.Context, token string) error {
+	id, email, err := r.jwtManager.VerifyEmailVerification(token)
+	if err != nil {
+		return ErrInvalidVerificationLink
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[id]
+	if !ok || u.email != email {
+		return ErrInvalidVerificationLink
+	}
+
+	if !u.emailVerifiedAt.Valid {
+		u.emailVerifiedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	}
+
+	return nil
+}
+
+// checkPassword must be called with mu held.
+func (r *MemoryRepository) checkPassword(id int, password string) (*memoryUser, error) {
+	u, ok := r.users[id]
+	if !ok {
+		return nil, ErrAccountNotFound
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(u.passwordHash), []byte(password)) != nil {
+		return nil, ErrWrongPassword
+	}
+
+	return u, nil
+}
+
+func (r *MemoryRepository) UpdateProfile(ctx context.Context, id int, data models.UserUpdateModel) (models.UserPublicModel, error) {
+	sensitive := data.Email != nil || data.NewPassword != nil
+	if sensitive && data.CurrentPassword == nil {
+		return models.UserPublicModel{}, ErrPasswordRequired
+	}
+
+	var passwordHash string
+	if data.NewPassword != nil {
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(*data.NewPassword), r.bcryptCost)
+		if err != nil {
+			return models.UserPublicModel{}, err
+		}
+		passwordHash = string(hashedPassword)
+	}
+
+	r.mu.Lock()
+
+	u, ok := r.users[id]
+	if !ok {
+		r.mu.Unlock()
+		return models.UserPublicModel{}, ErrAccountNotFound
+	}
+
+	if sensitive {
+		if _, err := r.checkPassword(id, *data.CurrentPassword); err != nil {
+			r.mu.Unlock()
+			return models.UserPublicModel{}, err
+		}
+	}
+
+	if data.Email != nil {
+		if other := r.byEmail(*data.Email); other != nil && other.id != id {
+			r.mu.Unlock()
+			return models.UserPublicModel{}, ErrEmailInUse
+		}
+	}
+
+	if data.FirstName != nil {
+		u.firstName = *data.FirstName
+	}
+	if data.LastName != nil {
+		u.lastName = *data.LastName
+	}
+	if data.Email != nil && *data.Email != u.email {
+		u.email = *data.Email
+		u.emailVerifiedAt = pgtype.Timestamptz{}
+	}
+	if passwordHash != "" {
+		u.passwordHash = passwordHash
+	}
+
+	public := u.public()
+	r.mu.Unlock()
+
+	if passwordHash != "" {
+		if err := r.RevokeSessions(ctx, id); err != nil {
+			return public, err
+		}
+	}
+
+	return public, nil
+}
+
+func (r *MemoryRepository) DeleteProfile(ctx context.Context, id int, data models.UserDeleteModel) error {
+	r.mu.Lock()
+
+	u, err := r.checkPassword(id, data.Password)
+	if err != nil {
+		r.mu.Unlock()
+		return err
+	}
+
+	if u.role == models.RoleAdmin {
+		r.mu.Unlock()
+		return ErrAdminUndeletable
+	}
+
+	u.firstName = "Deleted"
+	u.lastName = ""
+	u.email = fmt.Sprintf("deleted-%d@deleted.invalid", id)
+	u.passwordHash = ""
+	u.emailVerifiedAt = pgtype.Timestamptz{}
+
+	for hash, t := range r.resetTokens {
+		if t.userID == id {
+			delete(r.resetTokens, hash)
+		}
+	}
+	r.mu.Unlock()
+
+	return r.RevokeSessions(ctx, id)
+}
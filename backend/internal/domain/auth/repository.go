@@ -4,30 +4,91 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"time"
 
+	"github.com/euandresimoes/ecom-go/backend/internal/errs"
 	"github.com/euandresimoes/ecom-go/backend/internal/infra/cache"
 	"github.com/euandresimoes/ecom-go/backend/internal/infra/security"
 	"github.com/euandresimoes/ecom-go/backend/internal/models"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/redis/go-redis/v9"
+	"github.com/lucsky/cuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
-type Repository struct {
-	db         *pgxpool.Pool
-	redis      *redis.Client
-	jwtManager *security.JWTManager
+// querier is satisfied by both *pgxpool.Pool and pgx.Tx.
+type querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
 }
 
-func NewRepository(db *pgxpool.Pool, redis *redis.Client, jwtManager *security.JWTManager) *Repository {
-	return &Repository{db: db, redis: redis, jwtManager: jwtManager}
+var (
+	ErrRefreshTokenReused      = errs.Unauthorized("refresh token reuse detected")
+	ErrEmailNotVerified        = errs.Forbidden("email not verified")
+	ErrAccountDisabled         = errs.Forbidden("account disabled")
+	ErrEmailInUse              = errs.Conflict("email already in use")
+	ErrAccountNotFound         = errs.NotFound("account not found")
+	ErrInvalidCredentials      = errs.Unauthorized("invalid credentials")
+	ErrInvalidRefreshToken     = errs.Unauthorized("invalid refresh token")
+	ErrInvalidVerificationLink = errs.Validation("invalid verification link")
+	ErrRefreshTokenExpired     = errs.Unauthorized("refresh token expired")
+	ErrInvalidResetToken       = errs.Validation("invalid or expired reset token")
+	ErrWrongPassword           = errs.Forbidden("invalid credentials")
+	ErrPasswordRequired        = errs.Validation("current password required")
+	ErrAdminUndeletable        = errs.Forbidden("admin accounts cannot be deleted")
+)
+
+// Repository stores accounts, sessions and reset tokens. PostgresRepository
+// is the production implementation; MemoryRepository backs the tests.
+type Repository interface {
+	Register(ctx context.Context, data models.UserRegisterModel) (int, error)
+	Login(ctx context.Context, data models.UserLoginModel) (models.TokenPairModel, error)
+	Refresh(ctx context.Context, data models.UserRefreshModel) (models.TokenPairModel, error)
+	Logout(ctx context.Context, data models.UserRefreshModel, accessToken string) error
+	RevokeSessions(ctx context.Context, id int) error
+	Profile(ctx context.Context, id int) (models.UserPublicModel, error)
+	CreateResetToken(ctx context.Context, email string, ttl time.Duration) (string, error)
+	ResetPassword(ctx context.Context, data models.UserResetPasswordModel) error
+	EmailVerificationToken(ctx context.Context, id int, email string, expires time.Duration) (string, error)
+	VerificationTarget(ctx context.Context, email string) (int, bool, error)
+	VerifyEmail(ctx context.Context, token string) error
+	UpdateProfile(ctx context.Context, id int, data models.UserUpdateModel) (models.UserPublicModel, error)
+	DeleteProfile(ctx context.Context, id int, data models.UserDeleteModel) error
+}
+
+// PostgresRepository keeps accounts and sessions in Postgres and caches
+// profiles in the cache store.
+type PostgresRepository struct {
+	db                   *pgxpool.Pool
+	cache                cache.Store
+	jwtManager           *security.JWTManager
+	refreshExp           time.Duration
+	requireVerifiedLogin bool
+	bcryptCost           int
+	logger               *slog.Logger
 }
 
-func (r *Repository) Register(data models.UserRegisterModel) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(data.Password), 10)
+func NewPostgresRepository(db *pgxpool.Pool, cache cache.Store, jwtManager *security.JWTManager, refreshExp time.Duration, requireVerifiedLogin bool, bcryptCost int, logger *slog.Logger) *PostgresRepository {
+	return &PostgresRepository{
+		db:                   db,
+		cache:                cache,
+		jwtManager:           jwtManager,
+		refreshExp:           refreshExp,
+		requireVerifiedLogin: requireVerifiedLogin,
+		bcryptCost:           bcryptCost,
+		logger:               logger,
+	}
+}
+
+func (r *PostgresRepository) Register(ctx context.Context, data models.UserRegisterModel) (int, error) {
+	var id int
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(data.Password), r.bcryptCost)
 	if err != nil {
-		return err
+		return id, err
 	}
 
 	var exists bool
@@ -36,100 +97,746 @@ func (r *Repository) Register(data models.UserRegisterModel) error {
 		(SELECT 1 FROM users WHERE email = $1)
 	`
 	err = r.db.QueryRow(
-		context.Background(),
+		ctx,
 		query,
 		data.Email,
 	).Scan(&exists)
 	if err != nil {
-		return err
+		return id, err
 	}
 
 	if exists {
-		return errors.New("email already in use")
+		return id, ErrEmailInUse
 	}
 
 	query = `
 			INSERT INTO users (first_name, last_name, email, password_hash)
 			VALUES ($1, $2, $3, $4)
+			RETURNING id
 		`
-	_, err = r.db.Exec(
-		context.Background(),
+	err = r.db.QueryRow(
+		ctx,
 		query,
 		data.FirstName,
 		data.LastName,
 		data.Email,
 		string(hashedPassword),
-	)
+	).Scan(&id)
+	if err != nil {
+		// lost a race with another registration for the same email
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return id, ErrEmailInUse
+		}
+
+		return id, err
+	}
 
-	return err
+	return id, nil
 }
 
-func (r *Repository) Login(data models.UserLoginModel) (string, error) {
+func (r *PostgresRepository) Login(ctx context.Context, data models.UserLoginModel) (models.TokenPairModel, error) {
 	var (
-		id            int
-		role          models.UserRole
-		password_hash string
+		id              int
+		role            models.UserRole
+		password_hash   string
+		emailVerifiedAt pgtype.Timestamptz
+		disabledAt      pgtype.Timestamptz
 	)
 
 	query := `
-		SELECT id, role, password_hash
+		SELECT id, role, password_hash, email_verified_at, disabled_at
 		FROM users
 		WHERE email = $1
 	`
 	err := r.db.QueryRow(
-		context.Background(),
+		ctx,
 		query,
 		data.Email,
 	).Scan(
 		&id,
 		&role,
 		&password_hash,
+		&emailVerifiedAt,
+		&disabledAt,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return "", errors.New("account not found")
+			return models.TokenPairModel{}, ErrAccountNotFound
 		}
 
-		return "", err
+		return models.TokenPairModel{}, err
 	}
 
+	// any comparison error counts as a mismatch: anonymized accounts carry an
+	// empty hash that bcrypt rejects as malformed
 	err = bcrypt.CompareHashAndPassword([]byte(password_hash), []byte(data.Password))
-	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-		return "", errors.New("invalid credentials")
+	if err != nil {
+		return models.TokenPairModel{}, ErrInvalidCredentials
+	}
+
+	if disabledAt.Valid {
+		return models.TokenPairModel{}, ErrAccountDisabled
+	}
+
+	if r.requireVerifiedLogin && !emailVerifiedAt.Valid {
+		return models.TokenPairModel{}, ErrEmailNotVerified
+	}
+
+	return r.issueTokens(ctx, r.db, id, role, cuid.New())
+}
+
+// issueTokens signs an access token and stores a new refresh token in the
+// given family. q is either the pool or the transaction doing the rotation.
+func (r *PostgresRepository) issueTokens(ctx context.Context, q querier, id int, role models.UserRole, familyID string) (models.TokenPairModel, error) {
+	var pair models.TokenPairModel
+
+	refreshToken, err := security.NewOpaqueToken()
+	if err != nil {
+		return pair, err
+	}
+
+	query := `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	_, err = q.Exec(
+		ctx,
+		query,
+		id, familyID, security.HashToken(refreshToken), time.Now().Add(r.refreshExp),
+	)
+	if err != nil {
+		return pair, err
+	}
+
+	accessToken, err := r.jwtManager.Sign(id, role)
+	if err != nil {
+		return pair, err
+	}
+
+	pair.AccessToken = accessToken
+	pair.RefreshToken = refreshToken
+
+	return pair, nil
+}
+
+// Refresh rotates a refresh token. Presenting a token that was already
+// rotated or revoked means it leaked, so the whole family is revoked and the
+// legitimate holder has to log in again.
+func (r *PostgresRepository) Refresh(ctx context.Context, data models.UserRefreshModel) (models.TokenPairModel, error) {
+	var (
+		tokenID   int
+		userID    int
+		familyID  string
+		role      models.UserRole
+		expiresAt time.Time
+		revokedAt *time.Time
+		disabled  bool
+	)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return models.TokenPairModel{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		SELECT rt.id, rt.user_id, rt.family_id, u.role, rt.expires_at, rt.revoked_at, u.disabled_at IS NOT NULL
+		FROM refresh_tokens rt
+		JOIN users u ON u.id = rt.user_id
+		WHERE rt.token_hash = $1
+		FOR UPDATE OF rt
+	`
+	err = tx.QueryRow(
+		ctx,
+		query,
+		security.HashToken(data.RefreshToken),
+	).Scan(
+		&tokenID,
+		&userID,
+		&familyID,
+		&role,
+		&expiresAt,
+		&revokedAt,
+		&disabled,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.TokenPairModel{}, ErrInvalidRefreshToken
+		}
+
+		return models.TokenPairModel{}, err
 	}
 
-	return r.jwtManager.Sign(id, role)
+	if revokedAt != nil {
+		query = `
+			UPDATE refresh_tokens
+			SET revoked_at = NOW()
+			WHERE family_id = $1 AND revoked_at IS NULL
+		`
+		_, err = tx.Exec(
+			ctx,
+			query,
+			familyID,
+		)
+		if err != nil {
+			return models.TokenPairModel{}, err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return models.TokenPairModel{}, err
+		}
+
+		return models.TokenPairModel{}, ErrRefreshTokenReused
+	}
+
+	if time.Now().After(expiresAt) {
+		return models.TokenPairModel{}, ErrRefreshTokenExpired
+	}
+
+	if disabled {
+		return models.TokenPairModel{}, ErrAccountDisabled
+	}
+
+	query = `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE id = $1
+	`
+	_, err = tx.Exec(
+		ctx,
+		query,
+		tokenID,
+	)
+	if err != nil {
+		return models.TokenPairModel{}, err
+	}
+
+	pair, err := r.issueTokens(ctx, tx, userID, role, familyID)
+	if err != nil {
+		return pair, err
+	}
+
+	return pair, tx.Commit(ctx)
 }
 
-func (r *Repository) Profile(id float64) (models.UserPublicModel, error) {
+// Logout revokes every refresh token in the family of the given token, ending
+// that session on all of its rotations. The access token the request was made
+// with, if any, is denylisted as well.
+func (r *PostgresRepository) Logout(ctx context.Context, data models.UserRefreshModel, accessToken string) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE family_id = (SELECT family_id FROM refresh_tokens WHERE token_hash = $1)
+		AND revoked_at IS NULL
+	`
+	tag, err := r.db.Exec(
+		ctx,
+		query,
+		security.HashToken(data.RefreshToken),
+	)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrInvalidRefreshToken
+	}
+
+	if accessToken == "" {
+		return nil
+	}
+
+	token, err := r.jwtManager.Verify(accessToken)
+	if err != nil {
+		return nil
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil
+	}
+
+	return r.jwtManager.Revoke(ctx, claims)
+}
+
+// RevokeSessions ends every session of the user: all refresh tokens are
+// revoked and every access token issued so far stops being accepted.
+func (r *PostgresRepository) RevokeSessions(ctx context.Context, id int) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`
+	_, err := r.db.Exec(
+		ctx,
+		query,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	return r.jwtManager.RevokeUser(ctx, id)
+}
+
+func (r *PostgresRepository) Profile(ctx context.Context, id int) (models.UserPublicModel, error) {
 	var u models.UserPublicModel
 
-	redisKey := fmt.Sprintf("users:id:%v", id)
-	cachedProfile, _ := cache.Get[models.UserPublicModel](r.redis, redisKey)
+	redisKey := profileKey(id)
+	cachedProfile, _ := cache.Get[models.UserPublicModel](ctx, r.cache, redisKey)
 	if cachedProfile != nil {
 		return *cachedProfile, nil
 	}
 
 	query := `
 		SELECT
-		first_name, last_name, email
+		first_name, last_name, email, email_verified_at
 		FROM users
 		WHERE id = $1
 	`
 	err := r.db.QueryRow(
-		context.Background(),
+		ctx,
 		query,
 		id,
 	).Scan(
 		&u.FirstName,
 		&u.LastName,
 		&u.Email,
+		&u.EmailVerifiedAt,
 	)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return u, ErrAccountNotFound
+		}
+
 		return u, err
 	}
 
-	cache.Set(r.redis, redisKey, &u)
+	if err := cache.Set(ctx, r.cache, redisKey, &u); err != nil {
+		r.logger.WarnContext(ctx, "failed to cache profile", "key", redisKey, "error", err)
+	}
 
 	return u, nil
 }
+
+// CreateResetToken stores a new password reset token for the account with the
+// given email and returns it in plain text so it can be mailed. An unknown
+// email yields an empty token and no error, so callers cannot be used to probe
+// which addresses are registered.
+func (r *PostgresRepository) CreateResetToken(ctx context.Context, email string, ttl time.Duration) (string, error) {
+	var id int
+
+	query := `
+		SELECT id FROM users WHERE email = $1
+	`
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		email,
+	).Scan(&id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	token, err := security.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	query = `
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+	`
+	_, err = r.db.Exec(
+		ctx,
+		query,
+		id, security.HashToken(token), time.Now().Add(ttl),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// ResetPassword consumes a reset token and sets the new password. Every other
+// outstanding reset token of the user is burned too, and all existing sessions
+// are revoked.
+func (r *PostgresRepository) ResetPassword(ctx context.Context, data models.UserResetPasswordModel) error {
+	var (
+		tokenID int
+		userID  int
+	)
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(data.Password), r.bcryptCost)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		SELECT id, user_id
+		FROM password_reset_tokens
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		FOR UPDATE
+	`
+	err = tx.QueryRow(
+		ctx,
+		query,
+		security.HashToken(data.Token),
+	).Scan(
+		&tokenID,
+		&userID,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidResetToken
+		}
+
+		return err
+	}
+
+	query = `
+		UPDATE users
+		SET password_hash = $2, updated_at = NOW()
+		WHERE id = $1
+	`
+	_, err = tx.Exec(
+		ctx,
+		query,
+		userID, string(hashedPassword),
+	)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE password_reset_tokens
+		SET used_at = NOW()
+		WHERE user_id = $1 AND used_at IS NULL
+	`
+	_, err = tx.Exec(
+		ctx,
+		query,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	r.invalidateProfile(ctx, userID)
+
+	return r.RevokeSessions(ctx, userID)
+}
+
+func (r *PostgresRepository) EmailVerificationToken(ctx context.Context, id int, email string, expires time.Duration) (string, error) {
+	return r.jwtManager.SignEmailVerification(id, email, expires)
+}
+
+// VerificationTarget looks up the account a verification link should be sent
+// for, and whether its email has already been verified.
+func (r *PostgresRepository) VerificationTarget(ctx context.Context, email string) (int, bool, error) {
+	var (
+		id              int
+		emailVerifiedAt pgtype.Timestamptz
+	)
+
+	query := `
+		SELECT id, email_verified_at
+		FROM users
+		WHERE email = $1
+	`
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		email,
+	).Scan(
+		&id,
+		&emailVerifiedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, false, ErrAccountNotFound
+		}
+
+		return 0, false, err
+	}
+
+	return id, emailVerifiedAt.Valid, nil
+}
+
+// VerifyEmail marks the address carried by a verification link as verified.
+// The link is bound to the email it was sent to, so it stops working once the
+// user changes address. Verifying twice is a no-op.
+func (r *PostgresRepository) VerifyEmail(ctx context.Context, token string) error {
+	id, email, err := r.jwtManager.VerifyEmailVerification(token)
+	if err != nil {
+		return ErrInvalidVerificationLink
+	}
+
+	query := `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+		WHERE id = $1 AND email = $2
+	`
+	tag, err := r.db.Exec(
+		ctx,
+		query,
+		id, email,
+	)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrInvalidVerificationLink
+	}
+
+	r.invalidateProfile(ctx, id)
+
+	return nil
+}
+
+func (r *PostgresRepository) invalidateProfile(ctx context.Context, id int) {
+	redisKey := profileKey(id)
+	if err := cache.DeleteUnique(ctx, r.cache, redisKey); err != nil {
+		r.logger.WarnContext(ctx, "failed to invalidate cache", "key", redisKey, "error", err)
+	}
+}
+
+// profileKey is the cache key of a user's profile. Profile and
+// invalidateProfile must agree on it, so both build it here from the int id.
+func profileKey(id int) string {
+	return fmt.Sprintf("users:id:%d", id)
+}
+
+// checkPassword locks the user row and verifies password against it. It must
+// run inside tx so the row stays locked for the change that follows.
+func (r *PostgresRepository) checkPassword(ctx context.Context, tx pgx.Tx, id int, password string) (string, models.UserRole, error) {
+	var (
+		email         string
+		role          models.UserRole
+		password_hash string
+	)
+
+	query := `
+		SELECT email, role, password_hash
+		FROM users
+		WHERE id = $1
+		FOR UPDATE
+	`
+	err := tx.QueryRow(
+		ctx,
+		query,
+		id,
+	).Scan(
+		&email,
+		&role,
+		&password_hash,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", "", ErrAccountNotFound
+		}
+
+		return "", "", err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(password_hash), []byte(password))
+	if err != nil {
+		return "", "", ErrWrongPassword
+	}
+
+	return email, role, nil
+}
+
+// UpdateProfile applies a partial profile update. Changing the email or the
+// password requires the current password; a new email has to be verified
+// again and a new password ends every session, including the current one.
+func (r *PostgresRepository) UpdateProfile(ctx context.Context, id int, data models.UserUpdateModel) (models.UserPublicModel, error) {
+	var (
+		u            models.UserPublicModel
+		passwordHash *string
+	)
+
+	sensitive := data.Email != nil || data.NewPassword != nil
+	if sensitive && data.CurrentPassword == nil {
+		return u, ErrPasswordRequired
+	}
+
+	if data.NewPassword != nil {
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(*data.NewPassword), r.bcryptCost)
+		if err != nil {
+			return u, err
+		}
+
+		hash := string(hashedPassword)
+		passwordHash = &hash
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return u, err
+	}
+	defer tx.Rollback(ctx)
+
+	if sensitive {
+		_, _, err = r.checkPassword(ctx, tx, id, *data.CurrentPassword)
+		if err != nil {
+			return u, err
+		}
+	}
+
+	if data.Email != nil {
+		var exists bool
+
+		query := `
+			SELECT EXISTS
+			(SELECT 1 FROM users WHERE email = $1 AND id <> $2)
+		`
+		err = tx.QueryRow(
+			ctx,
+			query,
+			*data.Email, id,
+		).Scan(&exists)
+		if err != nil {
+			return u, err
+		}
+
+		if exists {
+			return u, ErrEmailInUse
+		}
+	}
+
+	query := `
+		UPDATE users
+		SET
+			first_name = COALESCE($2, first_name),
+			last_name = COALESCE($3, last_name),
+			email_verified_at = CASE
+				WHEN $4::text IS NOT NULL AND $4::text <> email THEN NULL
+				ELSE email_verified_at
+			END,
+			email = COALESCE($4, email),
+			password_hash = COALESCE($5, password_hash),
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING first_name, COALESCE(last_name, ''), email, email_verified_at
+	`
+	err = tx.QueryRow(
+		ctx,
+		query,
+		id, data.FirstName, data.LastName, data.Email, passwordHash,
+	).Scan(
+		&u.FirstName,
+		&u.LastName,
+		&u.Email,
+		&u.EmailVerifiedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return u, ErrAccountNotFound
+		}
+
+		// lost a race with another account taking the same email
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return u, ErrEmailInUse
+		}
+
+		return u, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return u, err
+	}
+
+	r.invalidateProfile(ctx, id)
+
+	if passwordHash != nil {
+		if err := r.RevokeSessions(ctx, id); err != nil {
+			return u, err
+		}
+	}
+
+	return u, nil
+}
+
+// DeleteProfile anonymizes the account instead of deleting the row, so orders
+// keep a valid user_id while no personal data is left behind. The email is
+// replaced with a unique placeholder and the password hash is blanked, which
+// makes the account impossible to log into.
+func (r *PostgresRepository) DeleteProfile(ctx context.Context, id int, data models.UserDeleteModel) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, role, err := r.checkPassword(ctx, tx, id, data.Password)
+	if err != nil {
+		return err
+	}
+
+	if role == models.RoleAdmin {
+		return ErrAdminUndeletable
+	}
+
+	query := `
+		UPDATE users
+		SET
+			first_name = 'Deleted',
+			last_name = '',
+			email = 'deleted-' || id || '@deleted.invalid',
+			password_hash = '',
+			email_verified_at = NULL,
+			deleted_at = NOW(),
+			updated_at = NOW()
+		WHERE id = $1
+	`
+	_, err = tx.Exec(
+		ctx,
+		query,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	queries := []string{
+		`DELETE FROM carts WHERE user_id = $1`,
+		`DELETE FROM password_reset_tokens WHERE user_id = $1`,
+	}
+	for _, query := range queries {
+		_, err = tx.Exec(
+			ctx,
+			query,
+			id,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	r.invalidateProfile(ctx, id)
+
+	return r.RevokeSessions(ctx, id)
+}
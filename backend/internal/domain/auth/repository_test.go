@@ -0,0 +1,336 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/cache"
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/security"
+	"github.com/euandresimoes/ecom-go/backend/internal/models"
+	"github.com/euandresimoes/ecom-go/backend/internal/testenv"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newPostgresRepository(t *testing.T) *PostgresRepository {
+	t.Helper()
+
+	db := testenv.Postgres(t)
+	redis, _ := testenv.Redis(t)
+
+	jwt := security.NewJWTManager("test-secret", 15*time.Minute, security.NewDenylist(redis, 15*time.Minute))
+
+	return NewPostgresRepository(db, cache.NewRedisStore(redis), jwt, time.Hour, false, bcrypt.MinCost, testenv.Logger(t))
+}
+
+func registerUser(t *testing.T, repo Repository, email string) int {
+	t.Helper()
+
+	id, err := repo.Register(context.Background(), models.UserRegisterModel{
+		FirstName: "Alice",
+		LastName:  "Smith",
+		Email:     email,
+		Password:  "password123",
+	})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	return id
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestPostgresRegisterAndLogin(t *testing.T) {
+	ctx := context.Background()
+	repo := newPostgresRepository(t)
+
+	registerUser(t, repo, "alice@example.com")
+
+	_, err := repo.Register(ctx, models.UserRegisterModel{FirstName: "Alice", Email: "alice@example.com", Password: "password123"})
+	if !errors.Is(err, ErrEmailInUse) {
+		t.Errorf("duplicate register: got %v, want %v", err, ErrEmailInUse)
+	}
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		want     error
+	}{
+		{"ok", "alice@example.com", "password123", nil},
+		{"wrong password", "alice@example.com", "wrongpassword", ErrInvalidCredentials},
+		{"unknown account", "bob@example.com", "password123", ErrAccountNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pair, err := repo.Login(ctx, models.UserLoginModel{Email: tt.email, Password: tt.password})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+			if err == nil && (pair.AccessToken == "" || pair.RefreshToken == "") {
+				t.Error("empty token pair")
+			}
+		})
+	}
+}
+
+func TestPostgresRefresh(t *testing.T) {
+	ctx := context.Background()
+	repo := newPostgresRepository(t)
+	registerUser(t, repo, "alice@example.com")
+
+	pair, err := repo.Login(ctx, models.UserLoginModel{Email: "alice@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := repo.Refresh(ctx, models.UserRefreshModel{RefreshToken: pair.RefreshToken})
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+
+	_, err = repo.Refresh(ctx, models.UserRefreshModel{RefreshToken: pair.RefreshToken})
+	if !errors.Is(err, ErrRefreshTokenReused) {
+		t.Errorf("reused token: got %v, want %v", err, ErrRefreshTokenReused)
+	}
+
+	// reuse revoked the whole family, including the token it was rotated to
+	_, err = repo.Refresh(ctx, models.UserRefreshModel{RefreshToken: rotated.RefreshToken})
+	if !errors.Is(err, ErrRefreshTokenReused) {
+		t.Errorf("rotated token after reuse: got %v, want %v", err, ErrRefreshTokenReused)
+	}
+
+	_, err = repo.Refresh(ctx, models.UserRefreshModel{RefreshToken: "unknown"})
+	if !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("unknown token: got %v, want %v", err, ErrInvalidRefreshToken)
+	}
+}
+
+func TestPostgresLogout(t *testing.T) {
+	ctx := context.Background()
+	repo := newPostgresRepository(t)
+	registerUser(t, repo, "alice@example.com")
+
+	pair, err := repo.Login(ctx, models.UserLoginModel{Email: "alice@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.Logout(ctx, models.UserRefreshModel{RefreshToken: pair.RefreshToken}, pair.AccessToken); err != nil {
+		t.Fatalf("logout: %v", err)
+	}
+
+	token, err := repo.jwtManager.Verify(pair.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := repo.jwtManager.IsRevoked(ctx, token.Claims.(jwt.MapClaims))
+	if err != nil || !revoked {
+		t.Errorf("access token after logout: revoked = %v, err = %v", revoked, err)
+	}
+
+	err = repo.Logout(ctx, models.UserRefreshModel{RefreshToken: pair.RefreshToken}, "")
+	if !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("second logout: got %v, want %v", err, ErrInvalidRefreshToken)
+	}
+}
+
+func TestPostgresUpdateProfile(t *testing.T) {
+	ctx := context.Background()
+	repo := newPostgresRepository(t)
+	id := registerUser(t, repo, "alice@example.com")
+	registerUser(t, repo, "bob@example.com")
+
+	if err := repo.VerifyEmail(ctx, mustVerificationToken(t, repo, id, "alice@example.com")); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	// cache the profile so the update has to invalidate it
+	if _, err := repo.Profile(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	// fields left out keep their values
+	u, err := repo.UpdateProfile(ctx, id, models.UserUpdateModel{FirstName: ptr("Alicia")})
+	if err != nil {
+		t.Fatalf("update name: %v", err)
+	}
+	if u.FirstName != "Alicia" || u.LastName != "Smith" || u.Email != "alice@example.com" || !u.EmailVerifiedAt.Valid {
+		t.Errorf("update name: got %+v", u)
+	}
+
+	profile, err := repo.Profile(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.FirstName != "Alicia" {
+		t.Errorf("profile after update: first_name = %q, stale cache?", profile.FirstName)
+	}
+
+	errTests := []struct {
+		name string
+		data models.UserUpdateModel
+		want error
+	}{
+		{"email without password", models.UserUpdateModel{Email: ptr("new@example.com")}, ErrPasswordRequired},
+		{"wrong password", models.UserUpdateModel{Email: ptr("new@example.com"), CurrentPassword: ptr("wrongpassword")}, ErrWrongPassword},
+		{"email taken", models.UserUpdateModel{Email: ptr("bob@example.com"), CurrentPassword: ptr("password123")}, ErrEmailInUse},
+	}
+
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := repo.UpdateProfile(ctx, id, tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// a new email has to be verified again
+	u, err = repo.UpdateProfile(ctx, id, models.UserUpdateModel{Email: ptr("alicia@example.com"), CurrentPassword: ptr("password123")})
+	if err != nil {
+		t.Fatalf("update email: %v", err)
+	}
+	if u.Email != "alicia@example.com" || u.EmailVerifiedAt.Valid {
+		t.Errorf("update email: got %+v", u)
+	}
+
+	pair, err := repo.Login(ctx, models.UserLoginModel{Email: "alicia@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a new password ends every session
+	_, err = repo.UpdateProfile(ctx, id, models.UserUpdateModel{NewPassword: ptr("newpassword123"), CurrentPassword: ptr("password123")})
+	if err != nil {
+		t.Fatalf("update password: %v", err)
+	}
+	if _, err := repo.Refresh(ctx, models.UserRefreshModel{RefreshToken: pair.RefreshToken}); err == nil {
+		t.Error("refresh token still valid after a password change")
+	}
+
+	// even one issued in the same second as the change
+	token, err := repo.jwtManager.Verify(pair.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked, err := repo.jwtManager.IsRevoked(ctx, token.Claims.(jwt.MapClaims)); err != nil || !revoked {
+		t.Errorf("access token after a password change: revoked = %v, err = %v", revoked, err)
+	}
+
+	if _, err := repo.Login(ctx, models.UserLoginModel{Email: "alicia@example.com", Password: "newpassword123"}); err != nil {
+		t.Errorf("login with new password: %v", err)
+	}
+}
+
+func TestPostgresProfileCacheLargeID(t *testing.T) {
+	ctx := context.Background()
+	repo := newPostgresRepository(t)
+
+	// ids from 1e6 up print as 1e+06 when formatted as float64
+	if _, err := repo.db.Exec(ctx, "SELECT setval('users_id_seq', 1000000)"); err != nil {
+		t.Fatal(err)
+	}
+	id := registerUser(t, repo, "alice@example.com")
+
+	if _, err := repo.Profile(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.UpdateProfile(ctx, id, models.UserUpdateModel{FirstName: ptr("Alicia")}); err != nil {
+		t.Fatal(err)
+	}
+
+	profile, err := repo.Profile(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.FirstName != "Alicia" {
+		t.Errorf("first_name = %q, stale cache", profile.FirstName)
+	}
+}
+
+func mustVerificationToken(t *testing.T, repo Repository, id int, email string) string {
+	t.Helper()
+
+	token, err := repo.EmailVerificationToken(context.Background(), id, email, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func TestPostgresDeleteProfile(t *testing.T) {
+	ctx := context.Background()
+	repo := newPostgresRepository(t)
+	id := registerUser(t, repo, "alice@example.com")
+
+	if _, err := repo.CreateResetToken(ctx, "alice@example.com", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	err := repo.DeleteProfile(ctx, id, models.UserDeleteModel{Password: "wrongpassword"})
+	if !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("wrong password: got %v, want %v", err, ErrWrongPassword)
+	}
+
+	if err := repo.DeleteProfile(ctx, id, models.UserDeleteModel{Password: "password123"}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	profile, err := repo.Profile(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.FirstName != "Deleted" || profile.Email == "alice@example.com" {
+		t.Errorf("profile not anonymized: %+v", profile)
+	}
+
+	if _, err := repo.Login(ctx, models.UserLoginModel{Email: "alice@example.com", Password: "password123"}); !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("login after delete: got %v, want %v", err, ErrAccountNotFound)
+	}
+
+	// the address is free to register again
+	registerUser(t, repo, "alice@example.com")
+}
+
+func TestPostgresResetPassword(t *testing.T) {
+	ctx := context.Background()
+	repo := newPostgresRepository(t)
+	registerUser(t, repo, "alice@example.com")
+
+	token, err := repo.CreateResetToken(ctx, "nobody@example.com", time.Hour)
+	if err != nil || token != "" {
+		t.Errorf("unknown email: got %q, %v", token, err)
+	}
+
+	first, err := repo.CreateResetToken(ctx, "alice@example.com", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := repo.CreateResetToken(ctx, "alice@example.com", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.ResetPassword(ctx, models.UserResetPasswordModel{Token: second, Password: "newpassword123"}); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+
+	// using one token burns every other outstanding token of the user
+	for _, token := range []string{first, second} {
+		err := repo.ResetPassword(ctx, models.UserResetPasswordModel{Token: token, Password: "otherpassword123"})
+		if !errors.Is(err, ErrInvalidResetToken) {
+			t.Errorf("token reused: got %v, want %v", err, ErrInvalidResetToken)
+		}
+	}
+
+	if _, err := repo.Login(ctx, models.UserLoginModel{Email: "alice@example.com", Password: "newpassword123"}); err != nil {
+		t.Errorf("login with new password: %v", err)
+	}
+}
@@ -1,23 +1,159 @@
 package auth
 
-import "github.com/euandresimoes/ecom-go/backend/internal/models"
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/mailer"
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/metrics"
+	"github.com/euandresimoes/ecom-go/backend/internal/models"
+)
+
+const (
+	resetTokenTTL        = time.Minute * 30
+	verificationTokenTTL = time.Hour * 48
+)
 
 type Service struct {
-	repo *Repository
+	repo   Repository
+	mailer mailer.Mailer
+	appURL string
+	logger *slog.Logger
+}
+
+func NewService(repo Repository, mailer mailer.Mailer, appURL string, logger *slog.Logger) *Service {
+	return &Service{repo: repo, mailer: mailer, appURL: appURL, logger: logger}
+}
+
+func (s *Service) Register(ctx context.Context, data models.UserRegisterModel) error {
+	id, err := s.repo.Register(ctx, data)
+	if err != nil {
+		return err
+	}
+
+	metrics.Registrations.Inc()
+
+	// the account exists at this point; a failed email can be retried
+	// through ResendVerification
+	if err := s.sendVerification(ctx, id, data.Email); err != nil {
+		s.logger.ErrorContext(ctx, "failed to send verification email", "user_id", id, "error", err)
+	}
+
+	return nil
+}
+
+// ResendVerification mails a fresh verification link. Like ForgotPassword it
+// does not reveal whether the address is registered.
+func (s *Service) ResendVerification(ctx context.Context, data models.UserResendVerificationModel) error {
+	id, verified, err := s.repo.VerificationTarget(ctx, data.Email)
+	if err != nil || verified {
+		return nil
+	}
+
+	return s.sendVerification(ctx, id, data.Email)
+}
+
+func (s *Service) VerifyEmail(ctx context.Context, token string) error {
+	return s.repo.VerifyEmail(ctx, token)
+}
+
+func (s *Service) sendVerification(ctx context.Context, id int, email string) error {
+	token, err := s.repo.EmailVerificationToken(ctx, id, email, verificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/api/v1/auth/verify?token=%s", s.appURL, url.QueryEscape(token))
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf(
+			"Welcome! Please confirm your email address by opening the link below within %v:\n%s",
+			verificationTokenTTL, link,
+		),
+	})
 }
 
-func NewService(repo *Repository) *Service {
-	return &Service{repo: repo}
+func (s *Service) Login(ctx context.Context, data models.UserLoginModel) (models.TokenPairModel, error) {
+	tokens, err := s.repo.Login(ctx, data)
+	if err != nil {
+		metrics.Logins.WithLabelValues("failure").Inc()
+		return tokens, err
+	}
+
+	metrics.Logins.WithLabelValues("success").Inc()
+
+	return tokens, nil
+}
+
+func (s *Service) Refresh(ctx context.Context, data models.UserRefreshModel) (models.TokenPairModel, error) {
+	return s.repo.Refresh(ctx, data)
+}
+
+func (s *Service) Logout(ctx context.Context, data models.UserRefreshModel, accessToken string) error {
+	return s.repo.Logout(ctx, data, accessToken)
+}
+
+func (s *Service) RevokeSessions(ctx context.Context, id int) error {
+	return s.repo.RevokeSessions(ctx, id)
+}
+
+func (s *Service) Profile(ctx context.Context, id int) (models.UserPublicModel, error) {
+	return s.repo.Profile(ctx, id)
 }
 
-func (s *Service) Register(data models.UserRegisterModel) error {
-	return s.repo.Register(data)
+func (s *Service) ForgotPassword(ctx context.Context, data models.UserForgotPasswordModel) error {
+	token, err := s.repo.CreateResetToken(ctx, data.Email, resetTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	if token == "" {
+		return nil
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", s.appURL, url.QueryEscape(token))
+
+	// a send failure is only possible for registered addresses, so reporting
+	// it would tell callers which emails have accounts
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      data.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"We received a request to reset your password.\n\nOpen the link below within %v to choose a new one:\n%s\n\nIf you did not ask for this, you can ignore this email.",
+			resetTokenTTL, link,
+		),
+	})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to send password reset email", "error", err)
+	}
+
+	return nil
 }
 
-func (s *Service) Login(data models.UserLoginModel) (string, error) {
-	return s.repo.Login(data)
+func (s *Service) ResetPassword(ctx context.Context, data models.UserResetPasswordModel) error {
+	return s.repo.ResetPassword(ctx, data)
+}
+
+func (s *Service) UpdateProfile(ctx context.Context, id int, data models.UserUpdateModel) (models.UserPublicModel, error) {
+	u, err := s.repo.UpdateProfile(ctx, id, data)
+	if err != nil {
+		return u, err
+	}
+
+	if data.Email != nil && !u.EmailVerifiedAt.Valid {
+		if err := s.sendVerification(ctx, id, u.Email); err != nil {
+			s.logger.ErrorContext(ctx, "failed to send verification email", "user_id", id, "error", err)
+		}
+	}
+
+	return u, nil
 }
 
-func (s *Service) Profile(id float64) (models.UserPublicModel, error) {
-	return s.repo.Profile(id)
+func (s *Service) DeleteProfile(ctx context.Context, id int, data models.UserDeleteModel) error {
+	return s.repo.DeleteProfile(ctx, id, data)
 }
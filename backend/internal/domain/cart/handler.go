@@ -0,0 +1,158 @@
+package cart
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/security"
+	"github.com/euandresimoes/ecom-go/backend/internal/middlewares"
+	"github.com/euandresimoes/ecom-go/backend/internal/models"
+	"github.com/euandresimoes/ecom-go/backend/internal/response"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+type Handler struct {
+	service   *Service
+	validator *validator.Validate
+}
+
+func NewHandler(service *Service, validator *validator.Validate, jwt *security.JWTManager) http.Handler {
+	h := &Handler{service: service, validator: validator}
+
+	r := chi.NewRouter()
+
+	r.Use(middlewares.Auth(jwt))
+
+	r.Get("/", h.Get)
+	r.Post("/items", h.AddItem)
+	r.Patch("/items", h.UpdateItem)
+	r.Delete("/items", h.RemoveItem)
+
+	return r
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	userID := int(r.Context().Value(models.UserIDKey).(float64))
+
+	cart, err := h.service.Get(r.Context(), userID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "cart found",
+		"data":    cart,
+	})
+}
+
+func (h *Handler) AddItem(w http.ResponseWriter, r *http.Request) {
+	userID := int(r.Context().Value(models.UserIDKey).(float64))
+
+	var data models.CartItemAddDto
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := h.validator.Struct(&data); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	cart, err := h.service.AddItem(r.Context(), userID, &data)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "item added to cart",
+		"data":    cart,
+	})
+}
+
+func (h *Handler) UpdateItem(w http.ResponseWriter, r *http.Request) {
+	userID := int(r.Context().Value(models.UserIDKey).(float64))
+	productID, err := strconv.Atoi(r.URL.Query().Get("product_id"))
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid product_id")
+		return
+	}
+
+	variantID, err := variantParam(r)
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid variant_id")
+		return
+	}
+
+	var data models.CartItemUpdateDto
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := h.validator.Struct(&data); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	cart, err := h.service.UpdateItem(r.Context(), userID, productID, variantID, &data)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "cart item updated",
+		"data":    cart,
+	})
+}
+
+func (h *Handler) RemoveItem(w http.ResponseWriter, r *http.Request) {
+	userID := int(r.Context().Value(models.UserIDKey).(float64))
+	productID, err := strconv.Atoi(r.URL.Query().Get("product_id"))
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid product_id")
+		return
+	}
+
+	variantID, err := variantParam(r)
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid variant_id")
+		return
+	}
+
+	cart, err := h.service.RemoveItem(r.Context(), userID, productID, variantID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "cart item removed",
+		"data":    cart,
+	})
+}
+
+// variantParam reads the optional variant_id query parameter. It is nil when
+// the request targets the plain product line.
+func variantParam(r *http.Request) (*int, error) {
+	raw := r.URL.Query().Get("variant_id")
+	if raw == "" {
+		return nil, nil
+	}
+
+	id, err := strconv.Atoi(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return &id, nil
+}
@@ -0,0 +1,258 @@
+package cart
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/euandresimoes/ecom-go/backend/internal/errs"
+	"github.com/euandresimoes/ecom-go/backend/internal/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repository struct {
+	db     *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewRepository(db *pgxpool.Pool, logger *slog.Logger) *Repository {
+	return &Repository{db: db, logger: logger}
+}
+
+func (r *Repository) cartID(ctx context.Context, userID int) (int, error) {
+	var id int
+
+	query := `
+		INSERT INTO carts (user_id)
+		VALUES ($1)
+		ON CONFLICT (user_id) DO UPDATE SET updated_at = NOW()
+		RETURNING id
+	`
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		userID,
+	).Scan(&id)
+
+	return id, err
+}
+
+func (r *Repository) Get(ctx context.Context, userID int) (models.CartModel, error) {
+	c := models.CartModel{Items: []models.CartItemModel{}}
+
+	cartID, err := r.cartID(ctx, userID)
+	if err != nil {
+		return c, err
+	}
+	c.ID = cartID
+
+	query := `
+		SELECT
+		p.id, ci.variant_id, p.public_id, p.name, v.sku,
+		COALESCE(v.price, p.price), ci.quantity, COALESCE(v.stock, p.stock),
+		COALESCE(v.price, p.price) * ci.quantity
+		FROM cart_items ci
+		JOIN products p ON p.id = ci.product_id
+		LEFT JOIN product_variants v ON v.id = ci.variant_id
+		WHERE ci.cart_id = $1
+		ORDER BY ci.created_at
+	`
+	rows, err := r.db.Query(
+		ctx,
+		query,
+		cartID,
+	)
+	if err != nil {
+		return c, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var i models.CartItemModel
+		err := rows.Scan(
+			&i.ProductID,
+			&i.VariantID,
+			&i.PublicID,
+			&i.Name,
+			&i.SKU,
+			&i.Price,
+			&i.Quantity,
+			&i.Stock,
+			&i.LineTotal,
+		)
+		if err != nil {
+			return c, err
+		}
+
+		c.Items = append(c.Items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return c, err
+	}
+
+	query = `
+		SELECT COALESCE(SUM(COALESCE(v.price, p.price) * ci.quantity), 0)
+		FROM cart_items ci
+		JOIN products p ON p.id = ci.product_id
+		LEFT JOIN product_variants v ON v.id = ci.variant_id
+		WHERE ci.cart_id = $1
+	`
+	err = r.db.QueryRow(
+		ctx,
+		query,
+		cartID,
+	).Scan(&c.Total)
+	if err != nil {
+		return c, err
+	}
+
+	return c, nil
+}
+
+// AddItem adds quantity to the cart line for the product, or for one of its
+// variants when a variant id is given, creating the line if needed. Variant
+// lines are checked against the variant's stock and plain lines against the
+// product's. The stock check happens inside the upsert, so concurrent adds of
+// the same line serialize on the cart_items row and cannot push the cart past
+// the stock.
+func (r *Repository) AddItem(ctx context.Context, userID int, data *models.CartItemAddDto) (models.CartModel, error) {
+	cartID, err := r.cartID(ctx, userID)
+	if err != nil {
+		return models.CartModel{}, err
+	}
+
+	query := `
+		INSERT INTO cart_items (cart_id, product_id, variant_id, quantity)
+		SELECT $1::int, p.id, v.id, $4::int
+		FROM products p
+		LEFT JOIN product_variants v ON v.id = $3 AND v.product_id = p.id
+		WHERE p.id = $2
+		AND ($3::int IS NULL OR v.id IS NOT NULL)
+		AND COALESCE(v.stock, p.stock) >= $4
+		ON CONFLICT (cart_id, product_id, variant_id) DO UPDATE
+		SET quantity = cart_items.quantity + EXCLUDED.quantity, updated_at = NOW()
+		WHERE cart_items.quantity + EXCLUDED.quantity <= COALESCE(
+			(SELECT stock FROM product_variants WHERE id = $3),
+			(SELECT stock FROM products WHERE id = $2)
+		)
+	`
+	tag, err := r.db.Exec(
+		ctx,
+		query,
+		cartID, data.ProductID, data.VariantID, data.Quantity,
+	)
+	if err != nil {
+		return models.CartModel{}, err
+	}
+
+	if tag.RowsAffected() == 0 {
+		var productExists, variantExists bool
+
+		query = `
+			SELECT
+			EXISTS (SELECT 1 FROM products WHERE id = $1),
+			$2::int IS NULL OR EXISTS (SELECT 1 FROM product_variants WHERE id = $2 AND product_id = $1)
+		`
+		err = r.db.QueryRow(
+			ctx,
+			query,
+			data.ProductID, data.VariantID,
+		).Scan(&productExists, &variantExists)
+		if err != nil {
+			return models.CartModel{}, err
+		}
+
+		if !productExists {
+			return models.CartModel{}, errs.NotFound("product not found")
+		}
+
+		if !variantExists {
+			return models.CartModel{}, errs.NotFound("variant not found")
+		}
+
+		r.logger.DebugContext(ctx, "cart item rejected", "reason", "insufficient stock", "cart_id", cartID, "product_id", data.ProductID, variantAttr(data.VariantID), "quantity", data.Quantity)
+		return models.CartModel{}, errs.Conflict("insufficient stock")
+	}
+
+	return r.Get(ctx, userID)
+}
+
+// UpdateItem sets the quantity of a cart line. variantID picks the variant
+// line of the product; nil picks the plain product line.
+func (r *Repository) UpdateItem(ctx context.Context, userID int, productID int, variantID *int, data *models.CartItemUpdateDto) (models.CartModel, error) {
+	cartID, err := r.cartID(ctx, userID)
+	if err != nil {
+		return models.CartModel{}, err
+	}
+
+	query := `
+		UPDATE cart_items
+		SET quantity = $4, updated_at = NOW()
+		WHERE cart_id = $1 AND product_id = $2 AND variant_id IS NOT DISTINCT FROM $3::int
+		AND $4 <= COALESCE(
+			(SELECT stock FROM product_variants WHERE id = $3),
+			(SELECT stock FROM products WHERE id = $2)
+		)
+	`
+	tag, err := r.db.Exec(
+		ctx,
+		query,
+		cartID, productID, variantID, data.Quantity,
+	)
+	if err != nil {
+		return models.CartModel{}, err
+	}
+
+	if tag.RowsAffected() == 0 {
+		var exists bool
+
+		query = `
+			SELECT EXISTS (
+				SELECT 1 FROM cart_items
+				WHERE cart_id = $1 AND product_id = $2 AND variant_id IS NOT DISTINCT FROM $3::int
+			)
+		`
+		err = r.db.QueryRow(
+			ctx,
+			query,
+			cartID, productID, variantID,
+		).Scan(&exists)
+		if err != nil {
+			return models.CartModel{}, err
+		}
+
+		if !exists {
+			return models.CartModel{}, errs.NotFound("item not found in cart")
+		}
+
+		r.logger.DebugContext(ctx, "cart item rejected", "reason", "insufficient stock", "cart_id", cartID, "product_id", productID, variantAttr(variantID), "quantity", data.Quantity)
+		return models.CartModel{}, errs.Conflict("insufficient stock")
+	}
+
+	return r.Get(ctx, userID)
+}
+
+func (r *Repository) RemoveItem(ctx context.Context, userID int, productID int, variantID *int) (models.CartModel, error) {
+	cartID, err := r.cartID(ctx, userID)
+	if err != nil {
+		return models.CartModel{}, err
+	}
+
+	query := `
+		DELETE FROM cart_items
+		WHERE cart_id = $1 AND product_id = $2 AND variant_id IS NOT DISTINCT FROM $3::int
+	`
+	tag, err := r.db.Exec(
+		ctx,
+		query,
+		cartID, productID, variantID,
+	)
+	if err != nil {
+		return models.CartModel{}, err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return models.CartModel{}, errs.NotFound("item not found in cart")
+	}
+
+	return r.Get(ctx, userID)
+}
@@ -0,0 +1,130 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/euandresimoes/ecom-go/backend/internal/errs"
+	"github.com/euandresimoes/ecom-go/backend/internal/models"
+	"github.com/euandresimoes/ecom-go/backend/internal/testenv"
+	"github.com/lucsky/cuid"
+)
+
+func TestAddItem(t *testing.T) {
+	ctx := context.Background()
+	db := testenv.Postgres(t)
+	repo := NewRepository(db, testenv.Logger(t))
+	userID := testenv.SeedUser(t, db)
+	productID := testenv.SeedProduct(t, db, "Espresso", "9.90", 3)
+
+	if _, err := repo.AddItem(ctx, userID, &models.CartItemAddDto{ProductID: 999, Quantity: 1}); !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("unknown product: got %v, want not found", err)
+	}
+	if _, err := repo.AddItem(ctx, userID, &models.CartItemAddDto{ProductID: productID, Quantity: 4}); !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("more than stock: got %v, want conflict", err)
+	}
+
+	c, err := repo.AddItem(ctx, userID, &models.CartItemAddDto{ProductID: productID, Quantity: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Items) != 1 || c.Items[0].Quantity != 2 {
+		t.Fatalf("add: got %+v", c.Items)
+	}
+
+	// the quantity already in the cart counts against stock
+	if _, err := repo.AddItem(ctx, userID, &models.CartItemAddDto{ProductID: productID, Quantity: 2}); !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("past stock: got %v, want conflict", err)
+	}
+
+	if _, err := repo.UpdateItem(ctx, userID, productID, nil, &models.CartItemUpdateDto{Quantity: 4}); !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("update past stock: got %v, want conflict", err)
+	}
+	if _, err := repo.UpdateItem(ctx, userID, 999, nil, &models.CartItemUpdateDto{Quantity: 1}); !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("update missing item: got %v, want not found", err)
+	}
+}
+
+func TestAddItemConcurrent(t *testing.T) {
+	ctx := context.Background()
+	db := testenv.Postgres(t)
+	repo := NewRepository(db, testenv.Logger(t))
+	userID := testenv.SeedUser(t, db)
+	productID := testenv.SeedProduct(t, db, "Espresso", "9.90", 5)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			repo.AddItem(ctx, userID, &models.CartItemAddDto{ProductID: productID, Quantity: 1})
+		})
+	}
+	wg.Wait()
+
+	c, err := repo.Get(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Items) != 1 || c.Items[0].Quantity != 5 {
+		t.Errorf("got %+v, want a single line of 5", c.Items)
+	}
+}
+
+func TestVariantItems(t *testing.T) {
+	ctx := context.Background()
+	db := testenv.Postgres(t)
+	repo := NewRepository(db, testenv.Logger(t))
+	userID := testenv.SeedUser(t, db)
+	productID := testenv.SeedProduct(t, db, "Espresso", "9.90", 10)
+	variantID := testenv.SeedVariant(t, db, productID, cuid.New(), "12.50", 2)
+	otherVariant := testenv.SeedVariant(t, db, testenv.SeedProduct(t, db, "Espresso", "9.90", 10), cuid.New(), "12.50", 5)
+
+	if _, err := repo.AddItem(ctx, userID, &models.CartItemAddDto{ProductID: productID, VariantID: &otherVariant, Quantity: 1}); !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("variant of another product: got %v, want not found", err)
+	}
+	// the variant's stock applies, not the product's
+	if _, err := repo.AddItem(ctx, userID, &models.CartItemAddDto{ProductID: productID, VariantID: &variantID, Quantity: 3}); !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("more than variant stock: got %v, want conflict", err)
+	}
+
+	if _, err := repo.AddItem(ctx, userID, &models.CartItemAddDto{ProductID: productID, VariantID: &variantID, Quantity: 2}); err != nil {
+		t.Fatal(err)
+	}
+	c, err := repo.AddItem(ctx, userID, &models.CartItemAddDto{ProductID: productID, Quantity: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.Items) != 2 {
+		t.Fatalf("got %d lines, want a variant line and a product line", len(c.Items))
+	}
+	v := c.Items[0]
+	if int(v.VariantID.Int32) != variantID || !v.SKU.Valid || v.Stock != 2 || v.Quantity != 2 {
+		t.Errorf("variant line: got %+v", v)
+	}
+	if p := c.Items[1]; p.VariantID.Valid || p.Stock != 10 || p.Quantity != 3 {
+		t.Errorf("product line: got %+v", p)
+	}
+	if total, _ := c.Total.Float64Value(); total.Float64 != 54.70 {
+		t.Errorf("total = %v, want 54.70", total.Float64)
+	}
+
+	if _, err := repo.UpdateItem(ctx, userID, productID, &variantID, &models.CartItemUpdateDto{Quantity: 3}); !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("update past variant stock: got %v, want conflict", err)
+	}
+	if _, err := repo.UpdateItem(ctx, userID, productID, nil, &models.CartItemUpdateDto{Quantity: 8}); err != nil {
+		t.Errorf("update product line: %v", err)
+	}
+
+	c, err = repo.RemoveItem(ctx, userID, productID, &variantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Items) != 1 || c.Items[0].VariantID.Valid || c.Items[0].Quantity != 8 {
+		t.Errorf("after removing the variant line: got %+v", c.Items)
+	}
+	if _, err := repo.RemoveItem(ctx, userID, productID, &variantID); !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("remove again: got %v, want not found", err)
+	}
+}
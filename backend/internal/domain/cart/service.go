@@ -0,0 +1,56 @@
+package cart
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/euandresimoes/ecom-go/backend/internal/models"
+)
+
+type Service struct {
+	repo   *Repository
+	logger *slog.Logger
+}
+
+func NewService(repo *Repository, logger *slog.Logger) *Service {
+	return &Service{repo: repo, logger: logger}
+}
+
+func (s *Service) Get(ctx context.Context, userID int) (models.CartModel, error) {
+	return s.repo.Get(ctx, userID)
+}
+
+func (s *Service) AddItem(ctx context.Context, userID int, data *models.CartItemAddDto) (models.CartModel, error) {
+	cart, err := s.repo.AddItem(ctx, userID, data)
+	if err != nil {
+		return cart, err
+	}
+
+	s.logger.DebugContext(ctx, "cart item added", "user_id", userID, "product_id", data.ProductID, variantAttr(data.VariantID), "quantity", data.Quantity)
+
+	return cart, nil
+}
+
+func (s *Service) UpdateItem(ctx context.Context, userID int, productID int, variantID *int, data *models.CartItemUpdateDto) (models.CartModel, error) {
+	return s.repo.UpdateItem(ctx, userID, productID, variantID, data)
+}
+
+func (s *Service) RemoveItem(ctx context.Context, userID int, productID int, variantID *int) (models.CartModel, error) {
+	cart, err := s.repo.RemoveItem(ctx, userID, productID, variantID)
+	if err != nil {
+		return cart, err
+	}
+
+	s.logger.DebugContext(ctx, "cart item removed", "user_id", userID, "product_id", productID, variantAttr(variantID))
+
+	return cart, nil
+}
+
+// variantAttr logs an optional variant id by value rather than as a pointer.
+func variantAttr(id *int) slog.Attr {
+	if id == nil {
+		return slog.Any("variant_id", nil)
+	}
+
+	return slog.Int("variant_id", *id)
+}
@@ -0,0 +1,137 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// checkTimeout bounds each dependency ping so a hung connection fails the
+// check instead of hanging the probe.
+const checkTimeout = 2 * time.Second
+
+type CheckModel struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Pool      any     `json:"pool"`
+}
+
+type PostgresPoolModel struct {
+	TotalConns    int32 `json:"total_conns"`
+	IdleConns     int32 `json:"idle_conns"`
+	AcquiredConns int32 `json:"acquired_conns"`
+	MaxConns      int32 `json:"max_conns"`
+}
+
+type RedisPoolModel struct {
+	TotalConns uint32 `json:"total_conns"`
+	IdleConns  uint32 `json:"idle_conns"`
+	StaleConns uint32 `json:"stale_conns"`
+	Hits       uint32 `json:"hits"`
+	Misses     uint32 `json:"misses"`
+	Timeouts   uint32 `json:"timeouts"`
+}
+
+type Handler struct {
+	db       *pgxpool.Pool
+	redis    *redis.Client
+	draining func() bool
+	logger   *slog.Logger
+}
+
+// NewHandler serves the probes. draining reports whether the server is
+// shutting down, in which case readiness fails regardless of dependencies.
+func NewHandler(db *pgxpool.Pool, redis *redis.Client, draining func() bool, logger *slog.Logger) *Handler {
+	return &Handler{db: db, redis: redis, draining: draining, logger: logger}
+}
+
+// Live answers as long as the process can serve HTTP at all.
+func (h *Handler) Live(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]any{
+		"status": "ok",
+	})
+}
+
+// Ready pings Postgres and Redis concurrently and answers 503 when either is
+// down or the server is draining. Failures are logged rather than returned,
+// since dial errors name internal hosts and ports.
+func (h *Handler) Ready(w http.ResponseWriter, r *http.Request) {
+	var (
+		wg       sync.WaitGroup
+		postgres CheckModel
+		cache    CheckModel
+	)
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		postgres = h.check(r.Context(), "postgres", h.db.Ping)
+		stat := h.db.Stat()
+		postgres.Pool = PostgresPoolModel{
+			TotalConns:    stat.TotalConns(),
+			IdleConns:     stat.IdleConns(),
+			AcquiredConns: stat.AcquiredConns(),
+			MaxConns:      stat.MaxConns(),
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		cache = h.check(r.Context(), "redis", func(ctx context.Context) error {
+			return h.redis.Ping(ctx).Err()
+		})
+		stat := h.redis.PoolStats()
+		cache.Pool = RedisPoolModel{
+			TotalConns: stat.TotalConns,
+			IdleConns:  stat.IdleConns,
+			StaleConns: stat.StaleConns,
+			Hits:       stat.Hits,
+			Misses:     stat.Misses,
+			Timeouts:   stat.Timeouts,
+		}
+	}()
+	wg.Wait()
+
+	status := "ok"
+	switch {
+	case h.draining():
+		status = "draining"
+	case postgres.Status != "up" || cache.Status != "up":
+		status = "unavailable"
+	}
+
+	if status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status": status,
+		"checks": map[string]CheckModel{
+			"postgres": postgres,
+			"redis":    cache,
+		},
+	})
+}
+
+func (h *Handler) check(ctx context.Context, name string, ping func(context.Context) error) CheckModel {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := ping(ctx)
+	c := CheckModel{
+		Status:    "up",
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		c.Status = "down"
+		h.logger.WarnContext(ctx, "readiness check failed", "check", name, "error", err)
+	}
+
+	return c
+}
@@ -0,0 +1,154 @@
+package order
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/security"
+	"github.com/euandresimoes/ecom-go/backend/internal/middlewares"
+	"github.com/euandresimoes/ecom-go/backend/internal/models"
+	"github.com/euandresimoes/ecom-go/backend/internal/response"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+type Handler struct {
+	service   *Service
+	validator *validator.Validate
+}
+
+func NewHandler(service *Service, validator *validator.Validate, jwt *security.JWTManager) http.Handler {
+	h := &Handler{service: service, validator: validator}
+
+	r := chi.NewRouter()
+
+	// customer routes
+	r.Group(func(protected chi.Router) {
+		protected.Use(middlewares.Auth(jwt))
+
+		protected.Post("/", h.Checkout)
+		protected.Get("/", h.GetAll)
+		protected.Get("/public", h.GetByPublicID)
+	})
+
+	// admin protected routes
+	r.Group(func(admin chi.Router) {
+		admin.Use(middlewares.Admin(jwt))
+
+		admin.Get("/admin", h.AdminGetAll)
+		admin.Patch("/admin/status", h.Transition)
+		admin.Get("/admin/history", h.History)
+	})
+
+	return r
+}
+
+func (h *Handler) Checkout(w http.ResponseWriter, r *http.Request) {
+	userID := int(r.Context().Value(models.UserIDKey).(float64))
+
+	order, err := h.service.Checkout(r.Context(), userID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusCreated,
+		"message": "order placed",
+		"data":    order,
+	})
+}
+
+func (h *Handler) GetAll(w http.ResponseWriter, r *http.Request) {
+	userID := int(r.Context().Value(models.UserIDKey).(float64))
+
+	orders, err := h.service.GetAll(r.Context(), userID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "orders found",
+		"data":    orders,
+	})
+}
+
+func (h *Handler) GetByPublicID(w http.ResponseWriter, r *http.Request) {
+	userID := int(r.Context().Value(models.UserIDKey).(float64))
+	publicID := r.URL.Query().Get("public_id")
+
+	order, err := h.service.GetByPublicID(r.Context(), userID, publicID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "order found",
+		"data":    order,
+	})
+}
+
+func (h *Handler) AdminGetAll(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+
+	orders, err := h.service.AdminGetAll(r.Context(), status)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "orders found",
+		"data":    orders,
+	})
+}
+
+func (h *Handler) Transition(w http.ResponseWriter, r *http.Request) {
+	adminID := int(r.Context().Value(models.UserIDKey).(float64))
+	publicID := r.URL.Query().Get("public_id")
+
+	var data models.OrderTransitionDto
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := h.validator.Struct(&data); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	order, err := h.service.Transition(r.Context(), adminID, publicID, &data)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "order status updated",
+		"data":    order,
+	})
+}
+
+func (h *Handler) History(w http.ResponseWriter, r *http.Request) {
+	publicID := r.URL.Query().Get("public_id")
+
+	history, err := h.service.History(r.Context(), publicID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "order status history",
+		"data":    history,
+	})
+}
@@ -0,0 +1,624 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/euandresimoes/ecom-go/backend/internal/errs"
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/cache"
+	"github.com/euandresimoes/ecom-go/backend/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lucsky/cuid"
+)
+
+var (
+	ErrEmptyCart         = errs.Validation("cart is empty")
+	ErrInsufficientStock = errs.Conflict("insufficient stock")
+	ErrInvalidTransition = errs.Conflict("invalid status transition")
+	ErrEmailNotVerified  = errs.Forbidden("email must be verified before checkout")
+	ErrOrderNotFound     = errs.NotFound("order not found")
+)
+
+type Repository struct {
+	db                      *pgxpool.Pool
+	cache                   cache.Store
+	requireVerifiedCheckout bool
+	logger                  *slog.Logger
+}
+
+func NewRepository(db *pgxpool.Pool, cache cache.Store, requireVerifiedCheckout bool, logger *slog.Logger) *Repository {
+	return &Repository{db: db, cache: cache, requireVerifiedCheckout: requireVerifiedCheckout, logger: logger}
+}
+
+type checkoutLine struct {
+	productID int
+	variantID pgtype.Int4
+	name      string
+	sku       pgtype.Text
+	price     pgtype.Numeric
+	stock     int
+	quantity  int
+}
+
+// Checkout turns the user's cart into an order. Product rows are locked in id
+// order so concurrent checkouts over the same products cannot deadlock, and
+// stock is only decremented once every line has been verified. Variant lines
+// take their price and stock from the variant. Variant rows are not locked,
+// so the decrements are guarded against an admin edit landing in between.
+func (r *Repository) Checkout(ctx context.Context, userID int) (models.OrderModel, error) {
+	var o models.OrderModel
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return o, err
+	}
+	defer tx.Rollback(ctx)
+
+	if r.requireVerifiedCheckout {
+		var verified bool
+
+		query := `
+			SELECT email_verified_at IS NOT NULL FROM users WHERE id = $1
+		`
+		err = tx.QueryRow(
+			ctx,
+			query,
+			userID,
+		).Scan(&verified)
+		if err != nil {
+			return o, err
+		}
+
+		if !verified {
+			return o, ErrEmailNotVerified
+		}
+	}
+
+	var cartID int
+
+	query := `
+		SELECT id FROM carts WHERE user_id = $1
+	`
+	err = tx.QueryRow(
+		ctx,
+		query,
+		userID,
+	).Scan(&cartID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return o, ErrEmptyCart
+		}
+
+		return o, err
+	}
+
+	query = `
+		SELECT
+		p.id, ci.variant_id, p.name, v.sku,
+		COALESCE(v.price, p.price), COALESCE(v.stock, p.stock), ci.quantity
+		FROM cart_items ci
+		JOIN products p ON p.id = ci.product_id
+		LEFT JOIN product_variants v ON v.id = ci.variant_id
+		WHERE ci.cart_id = $1
+		ORDER BY p.id, ci.variant_id
+		FOR UPDATE OF p
+	`
+	rows, err := tx.Query(
+		ctx,
+		query,
+		cartID,
+	)
+	if err != nil {
+		return o, err
+	}
+
+	var lines []checkoutLine
+	for rows.Next() {
+		var l checkoutLine
+		err := rows.Scan(
+			&l.productID,
+			&l.variantID,
+			&l.name,
+			&l.sku,
+			&l.price,
+			&l.stock,
+			&l.quantity,
+		)
+		if err != nil {
+			rows.Close()
+			return o, err
+		}
+
+		lines = append(lines, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return o, err
+	}
+
+	if len(lines) == 0 {
+		return o, ErrEmptyCart
+	}
+
+	for _, l := range lines {
+		if l.quantity > l.stock {
+			return o, fmt.Errorf("%w for %s", ErrInsufficientStock, l.name)
+		}
+	}
+
+	query = `
+		INSERT INTO orders (public_id, user_id)
+		VALUES ($1, $2)
+		RETURNING id
+	`
+	err = tx.QueryRow(
+		ctx,
+		query,
+		cuid.New(), userID,
+	).Scan(&o.ID)
+	if err != nil {
+		return o, err
+	}
+
+	for _, l := range lines {
+		query = `
+			UPDATE products
+			SET stock = stock - $2, updated_at = NOW()
+			WHERE id = $1 AND stock >= $2
+		`
+		stockID := l.productID
+		if l.variantID.Valid {
+			query = `
+				UPDATE product_variants
+				SET stock = stock - $2, updated_at = NOW()
+				WHERE id = $1 AND stock >= $2
+			`
+			stockID = int(l.variantID.Int32)
+		}
+		tag, err := tx.Exec(
+			ctx,
+			query,
+			stockID, l.quantity,
+		)
+		if err != nil {
+			return o, err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return o, fmt.Errorf("%w for %s", ErrInsufficientStock, l.name)
+		}
+
+		query = `
+			INSERT INTO order_items (order_id, product_id, variant_id, product_name, sku, unit_price, quantity, line_total)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $6::numeric * $7::int)
+		`
+		_, err = tx.Exec(
+			ctx,
+			query,
+			o.ID, l.productID, l.variantID, l.name, l.sku, l.price, l.quantity,
+		)
+		if err != nil {
+			return o, err
+		}
+	}
+
+	query = `
+		UPDATE orders
+		SET total = (SELECT SUM(line_total) FROM order_items WHERE order_id = $1)
+		WHERE id = $1
+		RETURNING id, public_id, user_id, status, total, created_at, updated_at
+	`
+	err = tx.QueryRow(
+		ctx,
+		query,
+		o.ID,
+	).Scan(
+		&o.ID,
+		&o.PublicID,
+		&o.UserID,
+		&o.Status,
+		&o.Total,
+		&o.CreatedAt,
+		&o.UpdatedAt,
+	)
+	if err != nil {
+		return o, err
+	}
+
+	query = `
+		INSERT INTO order_status_history (order_id, to_status, changed_by)
+		VALUES ($1, $2, $3)
+	`
+	_, err = tx.Exec(
+		ctx,
+		query,
+		o.ID, o.Status, userID,
+	)
+	if err != nil {
+		return o, err
+	}
+
+	query = `
+		DELETE FROM cart_items WHERE cart_id = $1
+	`
+	_, err = tx.Exec(
+		ctx,
+		query,
+		cartID,
+	)
+	if err != nil {
+		return o, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return o, err
+	}
+
+	redisKey := "products:*"
+	if err := cache.DeleteMany(ctx, r.cache, redisKey); err != nil {
+		r.logger.WarnContext(ctx, "failed to invalidate cache", "key", redisKey, "error", err)
+	}
+
+	o.Items, err = r.items(ctx, o.ID)
+	if err != nil {
+		return o, err
+	}
+
+	return o, nil
+}
+
+func (r *Repository) GetAll(ctx context.Context, userID int) ([]models.OrderModel, error) {
+	orders := []models.OrderModel{}
+
+	query := `
+		SELECT id, public_id, user_id, status, total, created_at, updated_at
+		FROM orders
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.Query(
+		ctx,
+		query,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var o models.OrderModel
+		err := rows.Scan(
+			&o.ID,
+			&o.PublicID,
+			&o.UserID,
+			&o.Status,
+			&o.Total,
+			&o.CreatedAt,
+			&o.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		orders = append(orders, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range orders {
+		orders[i].Items, err = r.items(ctx, orders[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return orders, nil
+}
+
+func (r *Repository) GetByPublicID(ctx context.Context, userID int, publicID string) (models.OrderModel, error) {
+	var o models.OrderModel
+
+	query := `
+		SELECT id, public_id, user_id, status, total, created_at, updated_at
+		FROM orders
+		WHERE public_id = $1 AND user_id = $2
+	`
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		publicID, userID,
+	).Scan(
+		&o.ID,
+		&o.PublicID,
+		&o.UserID,
+		&o.Status,
+		&o.Total,
+		&o.CreatedAt,
+		&o.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return o, ErrOrderNotFound
+		}
+
+		return o, err
+	}
+
+	o.Items, err = r.items(ctx, o.ID)
+	if err != nil {
+		return o, err
+	}
+
+	return o, nil
+}
+
+func (r *Repository) AdminGetAll(ctx context.Context, status string) ([]models.OrderModel, error) {
+	orders := []models.OrderModel{}
+
+	query := `
+		SELECT id, public_id, user_id, status, total, created_at, updated_at
+		FROM orders
+		WHERE ($1 = '' OR status::text = $1)
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.Query(
+		ctx,
+		query,
+		status,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var o models.OrderModel
+		err := rows.Scan(
+			&o.ID,
+			&o.PublicID,
+			&o.UserID,
+			&o.Status,
+			&o.Total,
+			&o.CreatedAt,
+			&o.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		orders = append(orders, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range orders {
+		orders[i].Items, err = r.items(ctx, orders[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return orders, nil
+}
+
+// Transition moves an order to a new status inside a transaction that holds
+// the order row lock, records the change in order_status_history and, for
+// cancellations, puts the ordered quantities back into the stock of the
+// variants or products they were taken from.
+func (r *Repository) Transition(ctx context.Context, adminID int, publicID string, data *models.OrderTransitionDto) (models.OrderModel, error) {
+	var (
+		o    models.OrderModel
+		from models.OrderStatus
+	)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return o, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		SELECT id, status
+		FROM orders
+		WHERE public_id = $1
+		FOR UPDATE
+	`
+	err = tx.QueryRow(
+		ctx,
+		query,
+		publicID,
+	).Scan(
+		&o.ID,
+		&from,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return o, ErrOrderNotFound
+		}
+
+		return o, err
+	}
+
+	if !canTransition(from, data.Status) {
+		return o, fmt.Errorf("%w from %s to %s", ErrInvalidTransition, from, data.Status)
+	}
+
+	query = `
+		UPDATE orders
+		SET status = $2, updated_at = NOW()
+		WHERE id = $1
+		RETURNING id, public_id, user_id, status, total, created_at, updated_at
+	`
+	err = tx.QueryRow(
+		ctx,
+		query,
+		o.ID, data.Status,
+	).Scan(
+		&o.ID,
+		&o.PublicID,
+		&o.UserID,
+		&o.Status,
+		&o.Total,
+		&o.CreatedAt,
+		&o.UpdatedAt,
+	)
+	if err != nil {
+		return o, err
+	}
+
+	query = `
+		INSERT INTO order_status_history (order_id, from_status, to_status, changed_by, note)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err = tx.Exec(
+		ctx,
+		query,
+		o.ID, from, data.Status, adminID, data.Note,
+	)
+	if err != nil {
+		return o, err
+	}
+
+	if data.Status == models.OrderCancelled {
+		query = `
+			UPDATE products p
+			SET stock = p.stock + oi.quantity, updated_at = NOW()
+			FROM order_items oi
+			WHERE oi.order_id = $1 AND p.id = oi.product_id AND oi.variant_id IS NULL
+		`
+		_, err = tx.Exec(
+			ctx,
+			query,
+			o.ID,
+		)
+		if err != nil {
+			return o, err
+		}
+
+		query = `
+			UPDATE product_variants v
+			SET stock = v.stock + oi.quantity, updated_at = NOW()
+			FROM order_items oi
+			WHERE oi.order_id = $1 AND v.id = oi.variant_id
+		`
+		_, err = tx.Exec(
+			ctx,
+			query,
+			o.ID,
+		)
+		if err != nil {
+			return o, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return o, err
+	}
+
+	if data.Status == models.OrderCancelled {
+		redisKey := "products:*"
+		if err := cache.DeleteMany(ctx, r.cache, redisKey); err != nil {
+			r.logger.WarnContext(ctx, "failed to invalidate cache", "key", redisKey, "error", err)
+		}
+	}
+
+	o.Items, err = r.items(ctx, o.ID)
+	if err != nil {
+		return o, err
+	}
+
+	return o, nil
+}
+
+func (r *Repository) History(ctx context.Context, publicID string) ([]models.OrderStatusHistoryModel, error) {
+	history := []models.OrderStatusHistoryModel{}
+
+	query := `
+		SELECT h.from_status, h.to_status, h.changed_by, h.note, h.created_at
+		FROM order_status_history h
+		JOIN orders o ON o.id = h.order_id
+		WHERE o.public_id = $1
+		ORDER BY h.id
+	`
+	rows, err := r.db.Query(
+		ctx,
+		query,
+		publicID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var h models.OrderStatusHistoryModel
+		err := rows.Scan(
+			&h.FromStatus,
+			&h.ToStatus,
+			&h.ChangedBy,
+			&h.Note,
+			&h.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		history = append(history, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(history) == 0 {
+		return nil, ErrOrderNotFound
+	}
+
+	return history, nil
+}
+
+func (r *Repository) items(ctx context.Context, orderID int) ([]models.OrderItemModel, error) {
+	items := []models.OrderItemModel{}
+
+	query := `
+		SELECT product_id, variant_id, product_name, sku, unit_price, quantity, line_total
+		FROM order_items
+		WHERE order_id = $1
+		ORDER BY id
+	`
+	rows, err := r.db.Query(
+		ctx,
+		query,
+		orderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var i models.OrderItemModel
+		err := rows.Scan(
+			&i.ProductID,
+			&i.VariantID,
+			&i.ProductName,
+			&i.SKU,
+			&i.UnitPrice,
+			&i.Quantity,
+			&i.LineTotal,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, i)
+	}
+
+	return items, rows.Err()
+}
@@ -0,0 +1,219 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/euandresimoes/ecom-go/backend/internal/domain/cart"
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/cache"
+	"github.com/euandresimoes/ecom-go/backend/internal/models"
+	"github.com/euandresimoes/ecom-go/backend/internal/testenv"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lucsky/cuid"
+)
+
+func stock(t *testing.T, db *pgxpool.Pool, productID int) int {
+	t.Helper()
+
+	var n int
+	if err := db.QueryRow(context.Background(), "SELECT stock FROM products WHERE id = $1", productID).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+
+	return n
+}
+
+func variantStock(t *testing.T, db *pgxpool.Pool, variantID int) int {
+	t.Helper()
+
+	var n int
+	if err := db.QueryRow(context.Background(), "SELECT stock FROM product_variants WHERE id = $1", variantID).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+
+	return n
+}
+
+func numericFloat(t *testing.T, n pgtype.Numeric) float64 {
+	t.Helper()
+
+	f, err := n.Float64Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return f.Float64
+}
+
+func TestCheckout(t *testing.T) {
+	ctx := context.Background()
+	db := testenv.Postgres(t)
+	redis, _ := testenv.Redis(t)
+	repo := NewRepository(db, cache.NewRedisStore(redis), false, testenv.Logger(t))
+	carts := cart.NewRepository(db, testenv.Logger(t))
+
+	userID := testenv.SeedUser(t, db)
+	espresso := testenv.SeedProduct(t, db, "Espresso", "9.90", 5)
+	decaf := testenv.SeedProduct(t, db, "Decaf", "7.25", 2)
+
+	if _, err := repo.Checkout(ctx, userID); !errors.Is(err, ErrEmptyCart) {
+		t.Errorf("empty cart: got %v, want %v", err, ErrEmptyCart)
+	}
+
+	for _, item := range []models.CartItemAddDto{
+		{ProductID: espresso, Quantity: 3},
+		{ProductID: decaf, Quantity: 2},
+	} {
+		if _, err := carts.AddItem(ctx, userID, &item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	o, err := repo.Checkout(ctx, userID)
+	if err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+
+	if o.Status != models.OrderPending || o.UserID != userID || len(o.Items) != 2 {
+		t.Fatalf("checkout: got %+v", o)
+	}
+	lineTotals := map[string]float64{}
+	for _, i := range o.Items {
+		lineTotals[i.ProductName] = numericFloat(t, i.LineTotal)
+	}
+	if lineTotals["Espresso"] != 29.70 || lineTotals["Decaf"] != 14.50 {
+		t.Errorf("line totals = %v, want Espresso 29.70 and Decaf 14.50", lineTotals)
+	}
+	if total := numericFloat(t, o.Total); total != 44.20 {
+		t.Errorf("total = %v, want 44.20", total)
+	}
+
+	if got := stock(t, db, espresso); got != 2 {
+		t.Errorf("espresso stock = %d, want 2", got)
+	}
+	if got := stock(t, db, decaf); got != 0 {
+		t.Errorf("decaf stock = %d, want 0", got)
+	}
+
+	c, err := carts.Get(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Items) != 0 {
+		t.Errorf("cart after checkout: got %d items, want 0", len(c.Items))
+	}
+
+	history, err := repo.History(ctx, o.PublicID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].ToStatus != models.OrderPending {
+		t.Errorf("history: got %+v", history)
+	}
+}
+
+func TestCheckoutInsufficientStock(t *testing.T) {
+	ctx := context.Background()
+	db := testenv.Postgres(t)
+	redis, _ := testenv.Redis(t)
+	repo := NewRepository(db, cache.NewRedisStore(redis), false, testenv.Logger(t))
+	carts := cart.NewRepository(db, testenv.Logger(t))
+
+	userID := testenv.SeedUser(t, db)
+	espresso := testenv.SeedProduct(t, db, "Espresso", "9.90", 5)
+	decaf := testenv.SeedProduct(t, db, "Decaf", "7.25", 2)
+
+	for _, item := range []models.CartItemAddDto{
+		{ProductID: espresso, Quantity: 3},
+		{ProductID: decaf, Quantity: 2},
+	} {
+		if _, err := carts.AddItem(ctx, userID, &item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// stock sold elsewhere after the items went into the cart
+	if _, err := db.Exec(ctx, "UPDATE products SET stock = 1 WHERE id = $1", decaf); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.Checkout(ctx, userID); !errors.Is(err, ErrInsufficientStock) {
+		t.Fatalf("got %v, want %v", err, ErrInsufficientStock)
+	}
+
+	// nothing was decremented and the cart is left as it was
+	if got := stock(t, db, espresso); got != 5 {
+		t.Errorf("espresso stock = %d, want 5", got)
+	}
+	c, err := carts.Get(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Items) != 2 {
+		t.Errorf("cart after failed checkout: got %d items, want 2", len(c.Items))
+	}
+}
+
+func TestCheckoutVariants(t *testing.T) {
+	ctx := context.Background()
+	db := testenv.Postgres(t)
+	redis, _ := testenv.Redis(t)
+	repo := NewRepository(db, cache.NewRedisStore(redis), false, testenv.Logger(t))
+	carts := cart.NewRepository(db, testenv.Logger(t))
+
+	userID := testenv.SeedUser(t, db)
+	adminID := testenv.SeedUser(t, db)
+	espresso := testenv.SeedProduct(t, db, "Espresso", "9.90", 5)
+	sku := "ESP-" + cuid.New()
+	kilo := testenv.SeedVariant(t, db, espresso, sku, "30.00", 4)
+
+	for _, item := range []models.CartItemAddDto{
+		{ProductID: espresso, VariantID: &kilo, Quantity: 3},
+		{ProductID: espresso, Quantity: 2},
+	} {
+		if _, err := carts.AddItem(ctx, userID, &item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	o, err := repo.Checkout(ctx, userID)
+	if err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+
+	if len(o.Items) != 2 {
+		t.Fatalf("checkout: got %+v", o.Items)
+	}
+	for _, i := range o.Items {
+		if i.VariantID.Valid {
+			if int(i.VariantID.Int32) != kilo || i.SKU.String != sku || numericFloat(t, i.UnitPrice) != 30 {
+				t.Errorf("variant item: got %+v", i)
+			}
+		} else if i.SKU.Valid || numericFloat(t, i.UnitPrice) != 9.90 {
+			t.Errorf("product item: got %+v", i)
+		}
+	}
+	if total := numericFloat(t, o.Total); total != 109.80 {
+		t.Errorf("total = %v, want 109.80", total)
+	}
+
+	// each line draws on its own stock
+	if got := variantStock(t, db, kilo); got != 1 {
+		t.Errorf("variant stock = %d, want 1", got)
+	}
+	if got := stock(t, db, espresso); got != 3 {
+		t.Errorf("product stock = %d, want 3", got)
+	}
+
+	if _, err := repo.Transition(ctx, adminID, o.PublicID, &models.OrderTransitionDto{Status: models.OrderCancelled}); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if got := variantStock(t, db, kilo); got != 4 {
+		t.Errorf("variant stock after cancel = %d, want 4", got)
+	}
+	if got := stock(t, db, espresso); got != 5 {
+		t.Errorf("product stock after cancel = %d, want 5", got)
+	}
+}
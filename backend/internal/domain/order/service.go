@@ -0,0 +1,76 @@
+package order
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/metrics"
+	"github.com/euandresimoes/ecom-go/backend/internal/models"
+)
+
+// transitions lists, for every status, the statuses an order may move to next.
+// Cancelled and refunded are terminal.
+var transitions = map[models.OrderStatus][]models.OrderStatus{
+	models.OrderPending:   {models.OrderPaid, models.OrderCancelled},
+	models.OrderPaid:      {models.OrderShipped, models.OrderCancelled, models.OrderRefunded},
+	models.OrderShipped:   {models.OrderDelivered},
+	models.OrderDelivered: {models.OrderRefunded},
+}
+
+func canTransition(from models.OrderStatus, to models.OrderStatus) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+type Service struct {
+	repo   *Repository
+	logger *slog.Logger
+}
+
+func NewService(repo *Repository, logger *slog.Logger) *Service {
+	return &Service{repo: repo, logger: logger}
+}
+
+func (s *Service) Checkout(ctx context.Context, userID int) (models.OrderModel, error) {
+	order, err := s.repo.Checkout(ctx, userID)
+	if err != nil {
+		return order, err
+	}
+
+	metrics.OrdersPlaced.Inc()
+	s.logger.InfoContext(ctx, "order placed", "order_id", order.PublicID, "user_id", userID, "items", len(order.Items))
+
+	return order, nil
+}
+
+func (s *Service) GetAll(ctx context.Context, userID int) ([]models.OrderModel, error) {
+	return s.repo.GetAll(ctx, userID)
+}
+
+func (s *Service) GetByPublicID(ctx context.Context, userID int, publicID string) (models.OrderModel, error) {
+	return s.repo.GetByPublicID(ctx, userID, publicID)
+}
+
+func (s *Service) AdminGetAll(ctx context.Context, status string) ([]models.OrderModel, error) {
+	return s.repo.AdminGetAll(ctx, status)
+}
+
+func (s *Service) Transition(ctx context.Context, adminID int, publicID string, data *models.OrderTransitionDto) (models.OrderModel, error) {
+	order, err := s.repo.Transition(ctx, adminID, publicID, data)
+	if err != nil {
+		return order, err
+	}
+
+	s.logger.InfoContext(ctx, "order status changed", "order_id", publicID, "status", order.Status, "admin_id", adminID)
+
+	return order, nil
+}
+
+func (s *Service) History(ctx context.Context, publicID string) ([]models.OrderStatusHistoryModel, error) {
+	return s.repo.History(ctx, publicID)
+}
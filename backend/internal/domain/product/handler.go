@@ -2,16 +2,23 @@ package product
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/euandresimoes/ecom-go/backend/internal/infra/security"
 	"github.com/euandresimoes/ecom-go/backend/internal/middlewares"
 	"github.com/euandresimoes/ecom-go/backend/internal/models"
+	"github.com/euandresimoes/ecom-go/backend/internal/response"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
 )
 
+// maxImageSize is the largest image upload accepted, in bytes.
+const maxImageSize = 5 << 20
+
 type Handler struct {
 	service   *Service
 	validator *validator.Validate
@@ -24,9 +31,12 @@ func NewHandler(service *Service, validator *validator.Validate, jwt *security.J
 
 	// public routes
 	r.Get("/", h.GetAll)
+	r.Get("/search", h.Search)
 	r.Get("/id", h.GetByID)
 	r.Get("/public", h.GetByPublicID)
 	r.Get("/category", h.GetAllCategories)
+	r.Get("/variant", h.GetVariants)
+	r.Get("/{id}/images", h.GetImages)
 
 	// admin protected routes
 	r.Group(func(protected chi.Router) {
@@ -37,6 +47,12 @@ func NewHandler(service *Service, validator *validator.Validate, jwt *security.J
 		protected.Patch("/", h.Update)
 		protected.Post("/category", h.CreateCategory)
 		protected.Delete("/category", h.DeleteCategory)
+		protected.Post("/variant", h.CreateVariant)
+		protected.Patch("/variant", h.UpdateVariant)
+		protected.Delete("/variant", h.DeleteVariant)
+		protected.Post("/{id}/images", h.UploadImage)
+		protected.Patch("/{id}/images/order", h.ReorderImages)
+		protected.Delete("/{id}/images/{imageID}", h.DeleteImage)
 	})
 
 	return r
@@ -46,33 +62,22 @@ func (h *Handler) CreateCategory(w http.ResponseWriter, r *http.Request) {
 	var data models.CategoryCreateDto
 
 	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]any{
-			"status": http.StatusBadRequest,
-			"error":  "invalid json",
-		})
+		response.Problem(w, r, http.StatusBadRequest, "invalid json")
 		return
 	}
 
 	if err := h.validator.Struct(&data); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]any{
-			"status": http.StatusBadRequest,
-			"error":  err.Error(),
-		})
+		response.Error(w, r, err)
 		return
 	}
 
-	category, err := h.service.CreateCategory(&data)
+	category, err := h.service.CreateCategory(r.Context(), &data)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]any{
-			"status": http.StatusBadRequest,
-			"error":  err.Error(),
-		})
+		response.Error(w, r, err)
 		return
 	}
 
+	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]any{
 		"status":  http.StatusCreated,
 		"message": "category created",
@@ -81,13 +86,9 @@ func (h *Handler) CreateCategory(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) GetAllCategories(w http.ResponseWriter, r *http.Request) {
-	categories, err := h.service.GetAllCategories()
+	categories, err := h.service.GetAllCategories(r.Context())
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]any{
-			"status": http.StatusBadRequest,
-			"error":  err.Error(),
-		})
+		response.Error(w, r, err)
 		return
 	}
 
@@ -99,15 +100,15 @@ func (h *Handler) GetAllCategories(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
-	id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
 
-	category, err := h.service.DeleteCategory(id)
+	category, err := h.service.DeleteCategory(r.Context(), id)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]any{
-			"status": http.StatusBadRequest,
-			"error":  err.Error(),
-		})
+		response.Error(w, r, err)
 		return
 	}
 
@@ -122,33 +123,22 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	var data models.ProductCreateDto
 
 	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]any{
-			"status": http.StatusBadRequest,
-			"error":  "invalid json",
-		})
+		response.Problem(w, r, http.StatusBadRequest, "invalid json")
 		return
 	}
 
 	if err := h.validator.Struct(&data); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]any{
-			"status": http.StatusBadRequest,
-			"error":  err.Error(),
-		})
+		response.Error(w, r, err)
 		return
 	}
 
-	product, err := h.service.Create(&data)
+	product, err := h.service.Create(r.Context(), &data)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]any{
-			"status": http.StatusBadRequest,
-			"error":  err.Error(),
-		})
+		response.Error(w, r, err)
 		return
 	}
 
+	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]any{
 		"status":  http.StatusCreated,
 		"message": "product created",
@@ -157,15 +147,15 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
-	id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
 
-	product, err := h.service.Delete(id)
+	product, err := h.service.Delete(r.Context(), id)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]any{
-			"status": http.StatusBadRequest,
-			"error":  err.Error(),
-		})
+		response.Error(w, r, err)
 		return
 	}
 
@@ -177,34 +167,26 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
-	id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
 
 	var data models.ProductUpdateDto
 	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]any{
-			"status": http.StatusBadRequest,
-			"error":  err.Error(),
-		})
+		response.Problem(w, r, http.StatusBadRequest, "invalid json")
 		return
 	}
 
 	if err := h.validator.Struct(&data); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]any{
-			"status": http.StatusBadRequest,
-			"error":  err.Error(),
-		})
+		response.Error(w, r, err)
 		return
 	}
 
-	product, err := h.service.Update(id, &data)
+	product, err := h.service.Update(r.Context(), id, &data)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]any{
-			"status": http.StatusBadRequest,
-			"error":  err.Error(),
-		})
+		response.Error(w, r, err)
 		return
 	}
 
@@ -215,14 +197,75 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// parseListQuery reads the listing filters from the query string, applying
+// the default sort, order and page size.
+func parseListQuery(params url.Values) (models.ProductListQuery, error) {
+	q := models.ProductListQuery{
+		Sort:   "created_at",
+		Order:  "desc",
+		Limit:  20,
+		Cursor: params.Get("cursor"),
+	}
+
+	if v := params.Get("category_id"); v != "" {
+		categoryID, err := strconv.Atoi(v)
+		if err != nil {
+			return q, err
+		}
+		q.CategoryID = &categoryID
+	}
+	if v := params.Get("min_price"); v != "" {
+		q.MinPrice = &v
+	}
+	if v := params.Get("max_price"); v != "" {
+		q.MaxPrice = &v
+	}
+	if v := params.Get("in_stock"); v != "" {
+		inStock, err := strconv.ParseBool(v)
+		if err != nil {
+			return q, err
+		}
+		q.InStock = inStock
+	}
+	if v := params.Get("sort"); v != "" {
+		q.Sort = v
+	}
+	if v := params.Get("order"); v != "" {
+		q.Order = v
+	}
+	if v := params.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return q, err
+		}
+		q.Limit = limit
+	}
+	if v := params.Get("page"); v != "" {
+		page, err := strconv.Atoi(v)
+		if err != nil {
+			return q, err
+		}
+		q.Page = page
+	}
+
+	return q, nil
+}
+
 func (h *Handler) GetAll(w http.ResponseWriter, r *http.Request) {
-	products, err := h.service.GetAll()
+	q, err := parseListQuery(r.URL.Query())
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid query parameters")
+		return
+	}
+
+	if err := h.validator.Struct(&q); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	products, err := h.service.GetAll(r.Context(), &q)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]any{
-			"status": http.StatusBadRequest,
-			"error":  err.Error(),
-		})
+		response.Error(w, r, err)
 		return
 	}
 
@@ -234,15 +277,15 @@ func (h *Handler) GetAll(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) {
-	id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
 
-	product, err := h.service.GetByID(id)
+	product, err := h.service.GetByID(r.Context(), id)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]any{
-			"status": http.StatusBadRequest,
-			"error":  err.Error(),
-		})
+		response.Error(w, r, err)
 		return
 	}
 
@@ -256,13 +299,9 @@ func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GetByPublicID(w http.ResponseWriter, r *http.Request) {
 	publicID := r.URL.Query().Get("public_id")
 
-	product, err := h.service.GetByPublicID(publicID)
+	product, err := h.service.GetByPublicID(r.Context(), publicID)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]any{
-			"status": http.StatusBadRequest,
-			"error":  err.Error(),
-		})
+		response.Error(w, r, err)
 		return
 	}
 
@@ -272,3 +311,272 @@ func (h *Handler) GetByPublicID(w http.ResponseWriter, r *http.Request) {
 		"data":    product,
 	})
 }
+
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	text := strings.TrimSpace(r.URL.Query().Get("q"))
+	if text == "" || len(text) > 100 {
+		response.Problem(w, r, http.StatusBadRequest, "q must be between 1 and 100 characters")
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 || limit > 50 {
+		limit = 20
+	}
+
+	products, err := h.service.Search(r.Context(), text, limit)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "products found",
+		"data":    products,
+	})
+}
+
+func (h *Handler) GetVariants(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(r.URL.Query().Get("product_id"))
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid product_id")
+		return
+	}
+
+	variants, err := h.service.GetVariants(r.Context(), productID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "variants found",
+		"data":    variants,
+	})
+}
+
+func (h *Handler) CreateVariant(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(r.URL.Query().Get("product_id"))
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid product_id")
+		return
+	}
+
+	var data models.ProductVariantCreateDto
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := h.validator.Struct(&data); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	variant, err := h.service.CreateVariant(r.Context(), productID, &data)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusCreated,
+		"message": "variant created",
+		"data":    variant,
+	})
+}
+
+func (h *Handler) UpdateVariant(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(r.URL.Query().Get("product_id"))
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid product_id")
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var data models.ProductVariantUpdateDto
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := h.validator.Struct(&data); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	variant, err := h.service.UpdateVariant(r.Context(), productID, id, &data)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "variant updated",
+		"data":    variant,
+	})
+}
+
+func (h *Handler) DeleteVariant(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(r.URL.Query().Get("product_id"))
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid product_id")
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	variant, err := h.service.DeleteVariant(r.Context(), productID, id)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "variant deleted",
+		"data":    variant,
+	})
+}
+
+// pathID reads a positive integer URL parameter.
+func pathID(r *http.Request, name string) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, name))
+	if err != nil || id < 1 {
+		return 0, false
+	}
+
+	return id, true
+}
+
+func (h *Handler) GetImages(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathID(r, "id")
+	if !ok {
+		response.Problem(w, r, http.StatusBadRequest, "invalid product id")
+		return
+	}
+
+	images, err := h.service.GetImages(r.Context(), productID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "images found",
+		"data":    images,
+	})
+}
+
+func (h *Handler) UploadImage(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathID(r, "id")
+	if !ok {
+		response.Problem(w, r, http.StatusBadRequest, "invalid product id")
+		return
+	}
+
+	// leave room for the multipart envelope around the file itself
+	r.Body = http.MaxBytesReader(w, r.Body, maxImageSize+1<<20)
+	if err := r.ParseMultipartForm(maxImageSize); err != nil {
+		response.Problem(w, r, http.StatusRequestEntityTooLarge, "image must be at most 5MB")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("image")
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "missing image file")
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxImageSize {
+		response.Problem(w, r, http.StatusRequestEntityTooLarge, "image must be at most 5MB")
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, maxImageSize))
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "could not read image")
+		return
+	}
+
+	image, err := h.service.UploadImage(r.Context(), productID, data)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusCreated,
+		"message": "image uploaded",
+		"data":    image,
+	})
+}
+
+func (h *Handler) ReorderImages(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathID(r, "id")
+	if !ok {
+		response.Problem(w, r, http.StatusBadRequest, "invalid product id")
+		return
+	}
+
+	var data models.ProductImageOrderDto
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := h.validator.Struct(&data); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	images, err := h.service.ReorderImages(r.Context(), productID, &data)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "images reordered",
+		"data":    images,
+	})
+}
+
+func (h *Handler) DeleteImage(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathID(r, "id")
+	imageID, imageOk := pathID(r, "imageID")
+	if !ok || !imageOk {
+		response.Problem(w, r, http.StatusBadRequest, "invalid product or image id")
+		return
+	}
+
+	image, err := h.service.DeleteImage(r.Context(), productID, imageID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "image deleted",
+		"data":    image,
+	})
+}
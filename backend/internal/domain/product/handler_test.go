@@ -0,0 +1,558 @@
+package product
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/security"
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/storage"
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/validation"
+	"github.com/euandresimoes/ecom-go/backend/internal/models"
+)
+
+type testServer struct {
+	v1       http.Handler
+	v2       http.Handler
+	dir      string
+	admin    string
+	customer string
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	dir := t.TempDir()
+	store, err := storage.NewLocal(dir, "/uploads")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwt := security.NewJWTManager("test-secret", 15*time.Minute, nil)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	service := NewService(NewMemoryRepository(), store, logger)
+
+	admin, err := jwt.Sign(1, models.RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	customer, err := jwt.Sign(2, models.RoleCustomer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testServer{
+		v1:       NewHandler(service, validation.Validator(), jwt),
+		v2:       NewHandlerV2(service, validation.Validator(), jwt),
+		dir:      dir,
+		admin:    admin,
+		customer: customer,
+	}
+}
+
+type testResponse struct {
+	Status  int             `json:"status"`
+	Message string          `json:"message"`
+	Detail  string          `json:"detail"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func serve(t *testing.T, h http.Handler, req *http.Request, token string) (int, testResponse) {
+	t.Helper()
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var res testResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("%s %s: decoding %q: %v", req.Method, req.URL, rec.Body.String(), err)
+	}
+
+	return rec.Code, res
+}
+
+func do(t *testing.T, h http.Handler, method string, path string, token string, body any) (int, testResponse) {
+	t.Helper()
+
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = bytes.NewBufferString(b)
+	default:
+		raw, err := json.Marshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+
+	return serve(t, h, httptest.NewRequest(method, path, reader), token)
+}
+
+// upload posts data as the image field of a multipart form.
+func upload(t *testing.T, h http.Handler, path string, token string, data []byte) (int, testResponse) {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	if data != nil {
+		part, err := form.CreateFormFile("image", "image.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(data)
+	}
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, path, body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	return serve(t, h, req, token)
+}
+
+// file maps the URL of an upload back to its path in local storage.
+func (s *testServer) file(url string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(strings.TrimPrefix(url, "/uploads/")))
+}
+
+func decode[T any](t *testing.T, raw json.RawMessage) T {
+	t.Helper()
+
+	var v T
+	if err := json.Unmarshal(raw, &v); err != nil {
+		t.Fatalf("decoding %s: %v", raw, err)
+	}
+
+	return v
+}
+
+func expect(t *testing.T, what string, status int, res testResponse, want int) {
+	t.Helper()
+
+	if status != want {
+		t.Errorf("%s: got %d %q, want %d", what, status, res.Detail, want)
+	}
+}
+
+func pngImage(t *testing.T) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	img.Set(4, 4, color.RGBA{R: 255, A: 255})
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func (s *testServer) createCategory(t *testing.T, name string) models.CategoryModel {
+	t.Helper()
+
+	status, res := do(t, s.v1, http.MethodPost, "/category", s.admin, map[string]string{"name": name})
+	if status != http.StatusCreated {
+		t.Fatalf("create category: got %d %q", status, res.Detail)
+	}
+
+	return decode[models.CategoryModel](t, res.Data)
+}
+
+func productBody(name string, price string, stock int, categoryID int32) map[string]any {
+	return map[string]any{
+		"name":         name,
+		"price":        json.Number(price),
+		"stock":        stock,
+		"category_id":  categoryID,
+		"weight_unit":  "g",
+		"weight_value": json.Number("250"),
+		"images":       []string{"https://cdn.example.com/" + name + ".jpg"},
+	}
+}
+
+func (s *testServer) createProduct(t *testing.T, body map[string]any) models.ProductModel {
+	t.Helper()
+
+	status, res := do(t, s.v1, http.MethodPost, "/", s.admin, body)
+	if status != http.StatusCreated {
+		t.Fatalf("create product: got %d %q", status, res.Detail)
+	}
+
+	return decode[models.ProductModel](t, res.Data)
+}
+
+func TestCategories(t *testing.T) {
+	s := newTestServer(t)
+
+	status, res := do(t, s.v1, http.MethodGet, "/category", "", nil)
+	expect(t, "list empty", status, res, http.StatusNotFound)
+
+	body := map[string]string{"name": "Coffee"}
+	status, res = do(t, s.v1, http.MethodPost, "/category", "", body)
+	expect(t, "create without token", status, res, http.StatusUnauthorized)
+	status, res = do(t, s.v1, http.MethodPost, "/category", s.customer, body)
+	expect(t, "create as customer", status, res, http.StatusForbidden)
+	status, res = do(t, s.v1, http.MethodPost, "/category", s.admin, "{")
+	expect(t, "create invalid json", status, res, http.StatusBadRequest)
+	status, res = do(t, s.v1, http.MethodPost, "/category", s.admin, map[string]string{"name": "ab"})
+	expect(t, "create short name", status, res, http.StatusUnprocessableEntity)
+
+	coffee := s.createCategory(t, "Coffee")
+	s.createCategory(t, "Tea")
+
+	status, res = do(t, s.v1, http.MethodPost, "/category", s.admin, body)
+	expect(t, "create duplicate", status, res, http.StatusConflict)
+
+	status, res = do(t, s.v1, http.MethodGet, "/category", "", nil)
+	expect(t, "list", status, res, http.StatusOK)
+	if got := decode[[]models.CategoryModel](t, res.Data); len(got) != 2 {
+		t.Errorf("list: got %d categories, want 2", len(got))
+	}
+
+	p := s.createProduct(t, productBody("Espresso", "9.90", 5, coffee.ID.Int32))
+
+	path := fmt.Sprintf("/category?id=%d", coffee.ID.Int32)
+	status, res = do(t, s.v1, http.MethodDelete, path, s.admin, nil)
+	expect(t, "delete with products", status, res, http.StatusConflict)
+	status, res = do(t, s.v1, http.MethodDelete, "/category?id=999", s.admin, nil)
+	expect(t, "delete unknown", status, res, http.StatusNotFound)
+	status, res = do(t, s.v1, http.MethodDelete, "/category?id=abc", s.admin, nil)
+	expect(t, "delete invalid id", status, res, http.StatusBadRequest)
+	status, res = do(t, s.v1, http.MethodDelete, path, s.customer, nil)
+	expect(t, "delete as customer", status, res, http.StatusForbidden)
+
+	status, res = do(t, s.v1, http.MethodDelete, fmt.Sprintf("/?id=%d", p.ID.Int32), s.admin, nil)
+	expect(t, "delete product", status, res, http.StatusOK)
+	status, res = do(t, s.v1, http.MethodDelete, path, s.admin, nil)
+	expect(t, "delete", status, res, http.StatusOK)
+}
+
+func TestProducts(t *testing.T) {
+	s := newTestServer(t)
+	coffee := s.createCategory(t, "Coffee")
+	tea := s.createCategory(t, "Tea")
+
+	t.Run("create errors", func(t *testing.T) {
+		body := productBody("Espresso", "9.90", 5, coffee.ID.Int32)
+
+		status, res := do(t, s.v1, http.MethodPost, "/", "", body)
+		expect(t, "no token", status, res, http.StatusUnauthorized)
+		status, res = do(t, s.v1, http.MethodPost, "/", s.customer, body)
+		expect(t, "customer", status, res, http.StatusForbidden)
+		status, res = do(t, s.v1, http.MethodPost, "/", s.admin, "[")
+		expect(t, "invalid json", status, res, http.StatusBadRequest)
+
+		status, res = do(t, s.v1, http.MethodPost, "/", s.admin, productBody("Espresso", "-1", 5, coffee.ID.Int32))
+		expect(t, "negative price", status, res, http.StatusUnprocessableEntity)
+
+		invalid := productBody("Espresso", "9.90", 5, coffee.ID.Int32)
+		invalid["weight_unit"] = "lb"
+		status, res = do(t, s.v1, http.MethodPost, "/", s.admin, invalid)
+		expect(t, "invalid weight unit", status, res, http.StatusUnprocessableEntity)
+
+		status, res = do(t, s.v1, http.MethodPost, "/", s.admin, productBody("Espresso", "9.90", 5, 999))
+		expect(t, "unknown category", status, res, http.StatusNotFound)
+	})
+
+	espresso := s.createProduct(t, productBody("Espresso Blend", "9.90", 5, coffee.ID.Int32))
+	decaf := s.createProduct(t, productBody("Decaf Roast", "7.50", -1, coffee.ID.Int32))
+	green := s.createProduct(t, productBody("Green Tea", "4.00", 12, tea.ID.Int32))
+
+	t.Run("get", func(t *testing.T) {
+		status, res := do(t, s.v1, http.MethodGet, fmt.Sprintf("/id?id=%d", espresso.ID.Int32), "", nil)
+		expect(t, "by id", status, res, http.StatusOK)
+		if got := decode[models.ProductModel](t, res.Data); got.PublicID != espresso.PublicID {
+			t.Errorf("by id: got %q, want %q", got.PublicID, espresso.PublicID)
+		}
+
+		status, res = do(t, s.v1, http.MethodGet, "/public?public_id="+green.PublicID, "", nil)
+		expect(t, "by public id", status, res, http.StatusOK)
+		if got := decode[models.ProductModel](t, res.Data); got.Name != "Green Tea" {
+			t.Errorf("by public id: got %q", got.Name)
+		}
+
+		status, res = do(t, s.v1, http.MethodGet, "/id?id=999", "", nil)
+		expect(t, "unknown id", status, res, http.StatusNotFound)
+		status, res = do(t, s.v1, http.MethodGet, "/public?public_id=nope", "", nil)
+		expect(t, "unknown public id", status, res, http.StatusNotFound)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		status, res := do(t, s.v1, http.MethodGet, "/?sort=price&order=asc", "", nil)
+		expect(t, "sorted by price", status, res, http.StatusOK)
+		list := decode[models.ProductListModel](t, res.Data)
+		if list.Total != 3 || len(list.Products) != 3 || list.Products[0].Name != "Green Tea" {
+			t.Errorf("sorted by price: got %+v", list)
+		}
+
+		status, res = do(t, s.v1, http.MethodGet, fmt.Sprintf("/?category_id=%d&in_stock=true", coffee.ID.Int32), "", nil)
+		expect(t, "filtered", status, res, http.StatusOK)
+		list = decode[models.ProductListModel](t, res.Data)
+		if list.Total != 1 || list.Products[0].Name != "Espresso Blend" {
+			t.Errorf("filtered: got %+v", list)
+		}
+
+		status, res = do(t, s.v1, http.MethodGet, "/?min_price=5&max_price=8", "", nil)
+		expect(t, "price range", status, res, http.StatusOK)
+		if list = decode[models.ProductListModel](t, res.Data); list.Total != 1 {
+			t.Errorf("price range: got %d products, want 1", list.Total)
+		}
+
+		// walk every page through the cursor
+		var (
+			names  []string
+			cursor string
+		)
+		path := "/?sort=name&order=asc&limit=2"
+		for range 3 {
+			status, res = do(t, s.v1, http.MethodGet, path, "", nil)
+			expect(t, "cursor page", status, res, http.StatusOK)
+			list = decode[models.ProductListModel](t, res.Data)
+			for _, p := range list.Products {
+				names = append(names, p.Name)
+			}
+			if list.NextCursor == nil {
+				break
+			}
+			cursor = *list.NextCursor
+			path = "/?sort=name&order=asc&limit=2&cursor=" + cursor
+		}
+		if fmt.Sprint(names) != "[Decaf Roast Espresso Blend Green Tea]" {
+			t.Errorf("cursor pages: got %v", names)
+		}
+
+		status, res = do(t, s.v1, http.MethodGet, "/?sort=name&order=asc&limit=2&page=2", "", nil)
+		expect(t, "offset page", status, res, http.StatusOK)
+		list = decode[models.ProductListModel](t, res.Data)
+		if len(list.Products) != 1 || list.NextCursor != nil {
+			t.Errorf("offset page: got %+v", list)
+		}
+
+		status, res = do(t, s.v1, http.MethodGet, "/?sort=stock", "", nil)
+		expect(t, "invalid sort", status, res, http.StatusUnprocessableEntity)
+		status, res = do(t, s.v1, http.MethodGet, "/?limit=abc", "", nil)
+		expect(t, "invalid limit", status, res, http.StatusBadRequest)
+		status, res = do(t, s.v1, http.MethodGet, "/?limit=500", "", nil)
+		expect(t, "limit too large", status, res, http.StatusUnprocessableEntity)
+		status, res = do(t, s.v1, http.MethodGet, "/?cursor=!!!", "", nil)
+		expect(t, "invalid cursor", status, res, http.StatusBadRequest)
+		status, res = do(t, s.v1, http.MethodGet, "/?sort=price&cursor="+cursor, "", nil)
+		expect(t, "cursor from another sort", status, res, http.StatusBadRequest)
+	})
+
+	t.Run("search", func(t *testing.T) {
+		status, res := do(t, s.v1, http.MethodGet, "/search?q=espr", "", nil)
+		expect(t, "prefix", status, res, http.StatusOK)
+		results := decode[[]models.ProductSearchResultModel](t, res.Data)
+		if len(results) != 1 || results[0].Name != "Espresso Blend" {
+			t.Errorf("prefix: got %+v", results)
+		}
+
+		status, res = do(t, s.v1, http.MethodGet, "/search?q=", "", nil)
+		expect(t, "empty", status, res, http.StatusBadRequest)
+		status, res = do(t, s.v1, http.MethodGet, "/search?q=%21%21", "", nil)
+		expect(t, "punctuation only", status, res, http.StatusUnprocessableEntity)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		path := fmt.Sprintf("/?id=%d", decaf.ID.Int32)
+
+		status, res := do(t, s.v1, http.MethodPatch, path, s.admin, map[string]any{"stock": 3, "name": "Decaf Roast II"})
+		expect(t, "update", status, res, http.StatusOK)
+		if got := decode[models.ProductModel](t, res.Data); got.Stock != 3 || got.Name != "Decaf Roast II" || got.CategoryID != int(coffee.ID.Int32) {
+			t.Errorf("update: got %+v", got)
+		}
+
+		status, res = do(t, s.v1, http.MethodPatch, path, s.customer, map[string]any{"stock": 3})
+		expect(t, "as customer", status, res, http.StatusForbidden)
+		status, res = do(t, s.v1, http.MethodPatch, path, s.admin, `{"stock": `)
+		expect(t, "invalid json", status, res, http.StatusBadRequest)
+		status, res = do(t, s.v1, http.MethodPatch, path, s.admin, map[string]any{"category_id": 999})
+		expect(t, "unknown category", status, res, http.StatusNotFound)
+		status, res = do(t, s.v1, http.MethodPatch, path, s.admin, map[string]any{"name": "ab"})
+		expect(t, "invalid name", status, res, http.StatusUnprocessableEntity)
+		status, res = do(t, s.v1, http.MethodPatch, "/?id=999", s.admin, map[string]any{"stock": 1})
+		expect(t, "unknown product", status, res, http.StatusNotFound)
+		status, res = do(t, s.v1, http.MethodPatch, "/?id=abc", s.admin, map[string]any{"stock": 1})
+		expect(t, "invalid id", status, res, http.StatusBadRequest)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		path := fmt.Sprintf("/?id=%d", decaf.ID.Int32)
+
+		status, res := do(t, s.v1, http.MethodDelete, path, s.customer, nil)
+		expect(t, "as customer", status, res, http.StatusForbidden)
+		status, res = do(t, s.v1, http.MethodDelete, "/?id=abc", s.admin, nil)
+		expect(t, "invalid id", status, res, http.StatusBadRequest)
+		status, res = do(t, s.v1, http.MethodGet, "/id?id=abc", "", nil)
+		expect(t, "get invalid id", status, res, http.StatusBadRequest)
+		status, res = do(t, s.v1, http.MethodDelete, path, s.admin, nil)
+		expect(t, "delete", status, res, http.StatusOK)
+		status, res = do(t, s.v1, http.MethodDelete, path, s.admin, nil)
+		expect(t, "delete again", status, res, http.StatusNotFound)
+		status, res = do(t, s.v1, http.MethodGet, fmt.Sprintf("/id?id=%d", decaf.ID.Int32), "", nil)
+		expect(t, "get deleted", status, res, http.StatusNotFound)
+	})
+}
+
+func TestVariants(t *testing.T) {
+	s := newTestServer(t)
+	coffee := s.createCategory(t, "Coffee")
+	p := s.createProduct(t, productBody("Espresso Blend", "9.90", 5, coffee.ID.Int32))
+
+	variant := func(sku string) map[string]any {
+		return map[string]any{
+			"sku":          sku,
+			"options":      map[string]string{"grind": "fine"},
+			"price":        json.Number("10.50"),
+			"stock":        3,
+			"weight_unit":  "kg",
+			"weight_value": json.Number("1"),
+		}
+	}
+
+	path := fmt.Sprintf("/variant?product_id=%d", p.ID.Int32)
+
+	status, res := do(t, s.v1, http.MethodPost, path, s.admin, variant("ESP-FINE-1KG"))
+	expect(t, "create", status, res, http.StatusCreated)
+	created := decode[models.ProductVariantModel](t, res.Data)
+
+	status, res = do(t, s.v1, http.MethodPost, path, s.admin, variant("ESP-FINE-1KG"))
+	expect(t, "duplicate sku", status, res, http.StatusConflict)
+	status, res = do(t, s.v1, http.MethodPost, "/variant?product_id=999", s.admin, variant("ESP-X"))
+	expect(t, "unknown product", status, res, http.StatusNotFound)
+	status, res = do(t, s.v1, http.MethodPost, path, s.admin, map[string]any{"sku": "ESP-X"})
+	expect(t, "missing fields", status, res, http.StatusUnprocessableEntity)
+	status, res = do(t, s.v1, http.MethodPost, path, s.customer, variant("ESP-X"))
+	expect(t, "as customer", status, res, http.StatusForbidden)
+
+	status, res = do(t, s.v1, http.MethodGet, "/variant?product_id="+fmt.Sprint(p.ID.Int32), "", nil)
+	expect(t, "list", status, res, http.StatusOK)
+	if got := decode[[]models.ProductVariantModel](t, res.Data); len(got) != 1 {
+		t.Errorf("list: got %d variants, want 1", len(got))
+	}
+
+	status, res = do(t, s.v1, http.MethodGet, fmt.Sprintf("/id?id=%d", p.ID.Int32), "", nil)
+	expect(t, "product with variants", status, res, http.StatusOK)
+	if got := decode[models.ProductModel](t, res.Data); len(got.Variants) != 1 {
+		t.Errorf("product with variants: got %d variants, want 1", len(got.Variants))
+	}
+
+	vpath := fmt.Sprintf("/variant?product_id=%d&id=%d", p.ID.Int32, created.ID.Int32)
+
+	// a variant cannot be changed through another product's id
+	other := s.createProduct(t, productBody("Decaf", "7.25", 5, coffee.ID.Int32))
+	otherPath := fmt.Sprintf("/variant?product_id=%d&id=%d", other.ID.Int32, created.ID.Int32)
+	status, res = do(t, s.v1, http.MethodPatch, otherPath, s.admin, map[string]any{"stock": 1})
+	expect(t, "update through other product", status, res, http.StatusNotFound)
+	status, res = do(t, s.v1, http.MethodDelete, otherPath, s.admin, nil)
+	expect(t, "delete through other product", status, res, http.StatusNotFound)
+	status, res = do(t, s.v1, http.MethodPatch, fmt.Sprintf("/variant?product_id=%d&id=abc", p.ID.Int32), s.admin, map[string]any{"stock": 1})
+	expect(t, "update invalid id", status, res, http.StatusBadRequest)
+	status, res = do(t, s.v1, http.MethodDelete, fmt.Sprintf("/variant?id=%d", created.ID.Int32), s.admin, nil)
+	expect(t, "delete without product_id", status, res, http.StatusBadRequest)
+
+	status, res = do(t, s.v1, http.MethodPatch, vpath, s.admin, map[string]any{"stock": 7})
+	expect(t, "update", status, res, http.StatusOK)
+	if got := decode[models.ProductVariantModel](t, res.Data); got.Stock != 7 || got.SKU != "ESP-FINE-1KG" {
+		t.Errorf("update: got %+v", got)
+	}
+	status, res = do(t, s.v1, http.MethodPatch, vpath, s.admin, map[string]any{"stock": -1})
+	expect(t, "negative stock", status, res, http.StatusUnprocessableEntity)
+	status, res = do(t, s.v1, http.MethodPatch, fmt.Sprintf("/variant?product_id=%d&id=999", p.ID.Int32), s.admin, map[string]any{"stock": 1})
+	expect(t, "update unknown", status, res, http.StatusNotFound)
+
+	status, res = do(t, s.v1, http.MethodDelete, vpath, s.admin, nil)
+	expect(t, "delete", status, res, http.StatusOK)
+	status, res = do(t, s.v1, http.MethodDelete, vpath, s.admin, nil)
+	expect(t, "delete again", status, res, http.StatusNotFound)
+}
+
+func TestImages(t *testing.T) {
+	s := newTestServer(t)
+	coffee := s.createCategory(t, "Coffee")
+	p := s.createProduct(t, productBody("Espresso Blend", "9.90", 5, coffee.ID.Int32))
+
+	path := fmt.Sprintf("/%d/images", p.ID.Int32)
+
+	status, res := upload(t, s.v1, path, s.admin, []byte("plain text, not an image"))
+	expect(t, "not an image", status, res, http.StatusUnsupportedMediaType)
+	status, res = upload(t, s.v1, path, s.admin, nil)
+	expect(t, "missing file", status, res, http.StatusBadRequest)
+	status, res = upload(t, s.v1, "/abc/images", s.admin, pngImage(t))
+	expect(t, "invalid product id", status, res, http.StatusBadRequest)
+	status, res = upload(t, s.v1, "/999/images", s.admin, pngImage(t))
+	expect(t, "unknown product", status, res, http.StatusNotFound)
+	status, res = upload(t, s.v1, path, s.customer, pngImage(t))
+	expect(t, "as customer", status, res, http.StatusForbidden)
+
+	var images []models.ProductImageModel
+	for range 2 {
+		status, res = upload(t, s.v1, path, s.admin, pngImage(t))
+		expect(t, "upload", status, res, http.StatusCreated)
+		images = append(images, decode[models.ProductImageModel](t, res.Data))
+	}
+
+	status, res = do(t, s.v1, http.MethodGet, path, "", nil)
+	expect(t, "list", status, res, http.StatusOK)
+	if got := decode[[]models.ProductImageModel](t, res.Data); len(got) != 2 || got[0].ID != images[0].ID {
+		t.Errorf("list: got %+v", got)
+	}
+
+	// uploads come first, followed by the product's external image
+	status, res = do(t, s.v1, http.MethodGet, fmt.Sprintf("/id?id=%d", p.ID.Int32), "", nil)
+	expect(t, "product", status, res, http.StatusOK)
+	got := decode[models.ProductModel](t, res.Data).Images
+	want := []string{images[0].URL, images[1].URL, p.Images[0]}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("product images: got %v, want %v", got, want)
+	}
+
+	order := path + "/order"
+	status, res = do(t, s.v1, http.MethodPatch, order, s.admin, map[string]any{"ids": []int{images[1].ID}})
+	expect(t, "reorder missing id", status, res, http.StatusUnprocessableEntity)
+	status, res = do(t, s.v1, http.MethodPatch, order, s.admin, map[string]any{"ids": []int{images[1].ID, images[1].ID}})
+	expect(t, "reorder duplicate id", status, res, http.StatusUnprocessableEntity)
+	status, res = do(t, s.v1, http.MethodPatch, order, s.admin, map[string]any{"ids": []int{}})
+	expect(t, "reorder empty", status, res, http.StatusUnprocessableEntity)
+
+	status, res = do(t, s.v1, http.MethodPatch, order, s.admin, map[string]any{"ids": []int{images[1].ID, images[0].ID}})
+	expect(t, "reorder", status, res, http.StatusOK)
+	if got := decode[[]models.ProductImageModel](t, res.Data); got[0].ID != images[1].ID || got[0].Position != 0 {
+		t.Errorf("reorder: got %+v", got)
+	}
+
+	status, res = do(t, s.v1, http.MethodDelete, path+"/999", s.admin, nil)
+	expect(t, "delete unknown", status, res, http.StatusNotFound)
+	status, res = do(t, s.v1, http.MethodDelete, path+"/abc", s.admin, nil)
+	expect(t, "delete invalid id", status, res, http.StatusBadRequest)
+
+	status, res = do(t, s.v1, http.MethodDelete, fmt.Sprintf("%s/%d", path, images[0].ID), s.admin, nil)
+	expect(t, "delete", status, res, http.StatusOK)
+
+	if _, err := os.Stat(s.file(images[0].URL)); !os.IsNotExist(err) {
+		t.Errorf("deleted image still in storage: %v", err)
+	}
+	if _, err := os.Stat(s.file(images[1].URL)); err != nil {
+		t.Errorf("remaining image missing from storage: %v", err)
+	}
+}
@@ -0,0 +1,333 @@
+package product
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/security"
+	"github.com/euandresimoes/ecom-go/backend/internal/middlewares"
+	"github.com/euandresimoes/ecom-go/backend/internal/models"
+	"github.com/euandresimoes/ecom-go/backend/internal/response"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+type productCtxKey struct{}
+
+// NewHandlerV2 serves the catalog under REST paths, addressing products by
+// public id and categories by id in the URL instead of the query string:
+//
+//	/products/{publicID}
+//	/products/{publicID}/variants/{variantID}
+//	/products/{publicID}/images/{imageID}
+//	/categories/{id}
+func NewHandlerV2(service *Service, validator *validator.Validate, jwt *security.JWTManager) http.Handler {
+	h := &Handler{service: service, validator: validator}
+
+	r := chi.NewRouter()
+
+	r.Route("/products", func(r chi.Router) {
+		r.Get("/", h.GetAll)
+		r.Get("/search", h.Search)
+		r.With(middlewares.Admin(jwt)).Post("/", h.Create)
+
+		r.Route("/{publicID}", func(r chi.Router) {
+			r.Group(func(public chi.Router) {
+				public.Use(h.productCtx)
+
+				public.Get("/", h.v2GetProduct)
+				public.Get("/variants", h.v2GetVariants)
+				public.Get("/images", h.v2GetImages)
+			})
+
+			// authorize before the lookup, so callers without access cannot
+			// probe which ids exist
+			r.Group(func(protected chi.Router) {
+				protected.Use(middlewares.Admin(jwt), h.productCtx)
+
+				protected.Patch("/", h.v2UpdateProduct)
+				protected.Delete("/", h.v2DeleteProduct)
+				protected.Post("/variants", h.v2CreateVariant)
+				protected.Patch("/variants/{variantID}", h.v2UpdateVariant)
+				protected.Delete("/variants/{variantID}", h.v2DeleteVariant)
+				protected.Post("/images", h.v2UploadImage)
+				protected.Patch("/images/order", h.v2ReorderImages)
+				protected.Delete("/images/{imageID}", h.v2DeleteImage)
+			})
+		})
+	})
+
+	r.Route("/categories", func(r chi.Router) {
+		r.Get("/", h.GetAllCategories)
+
+		r.Group(func(protected chi.Router) {
+			protected.Use(middlewares.Admin(jwt))
+
+			protected.Post("/", h.CreateCategory)
+			protected.Delete("/{id}", h.v2DeleteCategory)
+		})
+	})
+
+	return r
+}
+
+func v2Data(w http.ResponseWriter, status int, message string, data any) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  status,
+		"message": message,
+		"data":    data,
+	})
+}
+
+// productCtx resolves {publicID} once for every product sub-route and answers
+// 404 when it does not exist.
+func (h *Handler) productCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		publicID := chi.URLParam(r, "publicID")
+		if len(publicID) > 100 {
+			response.Problem(w, r, http.StatusBadRequest, "invalid product id")
+			return
+		}
+
+		product, err := h.service.GetByPublicID(r.Context(), publicID)
+		if err != nil {
+			response.Error(w, r, err)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), productCtxKey{}, product)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func productFromCtx(r *http.Request) models.ProductModel {
+	return r.Context().Value(productCtxKey{}).(models.ProductModel)
+}
+
+func (h *Handler) v2GetProduct(w http.ResponseWriter, r *http.Request) {
+	v2Data(w, http.StatusOK, "product found", productFromCtx(r))
+}
+
+func (h *Handler) v2UpdateProduct(w http.ResponseWriter, r *http.Request) {
+	p := productFromCtx(r)
+
+	var data models.ProductUpdateDto
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := h.validator.Struct(&data); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	product, err := h.service.Update(r.Context(), int(p.ID.Int32), &data)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	v2Data(w, http.StatusOK, "product updated", product)
+}
+
+func (h *Handler) v2DeleteProduct(w http.ResponseWriter, r *http.Request) {
+	p := productFromCtx(r)
+
+	product, err := h.service.Delete(r.Context(), int(p.ID.Int32))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	v2Data(w, http.StatusOK, "product deleted", product)
+}
+
+func (h *Handler) v2GetVariants(w http.ResponseWriter, r *http.Request) {
+	p := productFromCtx(r)
+
+	variants, err := h.service.GetVariants(r.Context(), int(p.ID.Int32))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	v2Data(w, http.StatusOK, "variants found", variants)
+}
+
+func (h *Handler) v2CreateVariant(w http.ResponseWriter, r *http.Request) {
+	p := productFromCtx(r)
+
+	var data models.ProductVariantCreateDto
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := h.validator.Struct(&data); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	variant, err := h.service.CreateVariant(r.Context(), int(p.ID.Int32), &data)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	v2Data(w, http.StatusCreated, "variant created", variant)
+}
+
+func (h *Handler) v2UpdateVariant(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r, "variantID")
+	if !ok {
+		response.Problem(w, r, http.StatusBadRequest, "invalid variant id")
+		return
+	}
+
+	var data models.ProductVariantUpdateDto
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := h.validator.Struct(&data); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	variant, err := h.service.UpdateVariant(r.Context(), int(productFromCtx(r).ID.Int32), id, &data)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	v2Data(w, http.StatusOK, "variant updated", variant)
+}
+
+func (h *Handler) v2DeleteVariant(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r, "variantID")
+	if !ok {
+		response.Problem(w, r, http.StatusBadRequest, "invalid variant id")
+		return
+	}
+
+	variant, err := h.service.DeleteVariant(r.Context(), int(productFromCtx(r).ID.Int32), id)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	v2Data(w, http.StatusOK, "variant deleted", variant)
+}
+
+func (h *Handler) v2GetImages(w http.ResponseWriter, r *http.Request) {
+	p := productFromCtx(r)
+
+	images, err := h.service.GetImages(r.Context(), int(p.ID.Int32))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	v2Data(w, http.StatusOK, "images found", images)
+}
+
+func (h *Handler) v2UploadImage(w http.ResponseWriter, r *http.Request) {
+	p := productFromCtx(r)
+
+	// leave room for the multipart envelope around the file itself
+	r.Body = http.MaxBytesReader(w, r.Body, maxImageSize+1<<20)
+	if err := r.ParseMultipartForm(maxImageSize); err != nil {
+		response.Problem(w, r, http.StatusRequestEntityTooLarge, "image must be at most 5MB")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("image")
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "missing image file")
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxImageSize {
+		response.Problem(w, r, http.StatusRequestEntityTooLarge, "image must be at most 5MB")
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, maxImageSize))
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "could not read image")
+		return
+	}
+
+	image, err := h.service.UploadImage(r.Context(), int(p.ID.Int32), data)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	v2Data(w, http.StatusCreated, "image uploaded", image)
+}
+
+func (h *Handler) v2ReorderImages(w http.ResponseWriter, r *http.Request) {
+	p := productFromCtx(r)
+
+	var data models.ProductImageOrderDto
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := h.validator.Struct(&data); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	images, err := h.service.ReorderImages(r.Context(), int(p.ID.Int32), &data)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	v2Data(w, http.StatusOK, "images reordered", images)
+}
+
+func (h *Handler) v2DeleteImage(w http.ResponseWriter, r *http.Request) {
+	p := productFromCtx(r)
+
+	imageID, ok := pathID(r, "imageID")
+	if !ok {
+		response.Problem(w, r, http.StatusBadRequest, "invalid image id")
+		return
+	}
+
+	image, err := h.service.DeleteImage(r.Context(), int(p.ID.Int32), imageID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	v2Data(w, http.StatusOK, "image deleted", image)
+}
+
+func (h *Handler) v2DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id < 1 {
+		response.Problem(w, r, http.StatusBadRequest, "invalid category id")
+		return
+	}
+
+	category, err := h.service.DeleteCategory(r.Context(), id)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	v2Data(w, http.StatusOK, "category deleted", category)
+}
@@ -0,0 +1,119 @@
+package product
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/euandresimoes/ecom-go/backend/internal/models"
+)
+
+func TestV2Categories(t *testing.T) {
+	s := newTestServer(t)
+
+	status, res := do(t, s.v2, http.MethodPost, "/categories", s.customer, map[string]string{"name": "Coffee"})
+	expect(t, "create as customer", status, res, http.StatusForbidden)
+
+	status, res = do(t, s.v2, http.MethodPost, "/categories", s.admin, map[string]string{"name": "Coffee"})
+	expect(t, "create", status, res, http.StatusCreated)
+	coffee := decode[models.CategoryModel](t, res.Data)
+
+	status, res = do(t, s.v2, http.MethodGet, "/categories", "", nil)
+	expect(t, "list", status, res, http.StatusOK)
+
+	status, res = do(t, s.v2, http.MethodDelete, "/categories/abc", s.admin, nil)
+	expect(t, "delete invalid id", status, res, http.StatusBadRequest)
+	status, res = do(t, s.v2, http.MethodDelete, "/categories/999", s.admin, nil)
+	expect(t, "delete unknown", status, res, http.StatusNotFound)
+	status, res = do(t, s.v2, http.MethodDelete, fmt.Sprintf("/categories/%d", coffee.ID.Int32), s.admin, nil)
+	expect(t, "delete", status, res, http.StatusOK)
+}
+
+func TestV2Products(t *testing.T) {
+	s := newTestServer(t)
+	coffee := s.createCategory(t, "Coffee")
+
+	status, res := do(t, s.v2, http.MethodPost, "/products", s.admin, productBody("Espresso Blend", "9.90", 5, coffee.ID.Int32))
+	expect(t, "create", status, res, http.StatusCreated)
+	p := decode[models.ProductModel](t, res.Data)
+	other := s.createProduct(t, productBody("Decaf Roast", "7.50", 2, coffee.ID.Int32))
+
+	path := "/products/" + p.PublicID
+
+	status, res = do(t, s.v2, http.MethodGet, "/products?sort=price&order=asc", "", nil)
+	expect(t, "list", status, res, http.StatusOK)
+	if list := decode[models.ProductListModel](t, res.Data); list.Total != 2 {
+		t.Errorf("list: got %d products, want 2", list.Total)
+	}
+	status, res = do(t, s.v2, http.MethodGet, "/products/search?q=blend", "", nil)
+	expect(t, "search", status, res, http.StatusOK)
+
+	status, res = do(t, s.v2, http.MethodGet, path, "", nil)
+	expect(t, "get", status, res, http.StatusOK)
+	status, res = do(t, s.v2, http.MethodGet, "/products/unknown", "", nil)
+	expect(t, "get unknown", status, res, http.StatusNotFound)
+
+	status, res = do(t, s.v2, http.MethodPatch, path, s.admin, map[string]any{"price": json.Number("11.00")})
+	expect(t, "update", status, res, http.StatusOK)
+	status, res = do(t, s.v2, http.MethodPatch, path, s.admin, "{")
+	expect(t, "update invalid json", status, res, http.StatusBadRequest)
+	status, res = do(t, s.v2, http.MethodPatch, path, "", map[string]any{"stock": 1})
+	expect(t, "update without token", status, res, http.StatusUnauthorized)
+	// access is checked before the lookup, so unknown ids are not revealed
+	status, res = do(t, s.v2, http.MethodPatch, "/products/unknown", "", map[string]any{"stock": 1})
+	expect(t, "update unknown without token", status, res, http.StatusUnauthorized)
+	status, res = do(t, s.v2, http.MethodDelete, "/products/unknown", s.customer, nil)
+	expect(t, "delete unknown as customer", status, res, http.StatusForbidden)
+
+	variant := map[string]any{
+		"sku":          "ESP-1KG",
+		"price":        json.Number("20"),
+		"stock":        1,
+		"weight_unit":  "kg",
+		"weight_value": json.Number("1"),
+	}
+	status, res = do(t, s.v2, http.MethodPost, path+"/variants", s.admin, variant)
+	expect(t, "create variant", status, res, http.StatusCreated)
+	v := decode[models.ProductVariantModel](t, res.Data)
+
+	status, res = do(t, s.v2, http.MethodGet, path+"/variants", "", nil)
+	expect(t, "list variants", status, res, http.StatusOK)
+
+	// a variant cannot be reached through another product's path
+	otherVariant := fmt.Sprintf("/products/%s/variants/%d", other.PublicID, v.ID.Int32)
+	status, res = do(t, s.v2, http.MethodPatch, otherVariant, s.admin, map[string]any{"stock": 9})
+	expect(t, "update through other product", status, res, http.StatusNotFound)
+	status, res = do(t, s.v2, http.MethodDelete, otherVariant, s.admin, nil)
+	expect(t, "delete through other product", status, res, http.StatusNotFound)
+
+	vpath := fmt.Sprintf("%s/variants/%d", path, v.ID.Int32)
+	status, res = do(t, s.v2, http.MethodPatch, path+"/variants/abc", s.admin, map[string]any{"stock": 9})
+	expect(t, "update invalid variant id", status, res, http.StatusBadRequest)
+	status, res = do(t, s.v2, http.MethodPatch, vpath, s.admin, map[string]any{"stock": 9})
+	expect(t, "update variant", status, res, http.StatusOK)
+	status, res = do(t, s.v2, http.MethodDelete, vpath, s.admin, nil)
+	expect(t, "delete variant", status, res, http.StatusOK)
+
+	status, res = upload(t, s.v2, path+"/images", s.admin, pngImage(t))
+	expect(t, "upload image", status, res, http.StatusCreated)
+	image := decode[models.ProductImageModel](t, res.Data)
+
+	status, res = do(t, s.v2, http.MethodGet, path+"/images", "", nil)
+	expect(t, "list images", status, res, http.StatusOK)
+	status, res = do(t, s.v2, http.MethodPatch, path+"/images/order", s.admin, map[string]any{"ids": []int{image.ID}})
+	expect(t, "reorder images", status, res, http.StatusOK)
+
+	// images are scoped to the product in the URL as well
+	status, res = do(t, s.v2, http.MethodDelete, fmt.Sprintf("/products/%s/images/%d", other.PublicID, image.ID), s.admin, nil)
+	expect(t, "delete image through other product", status, res, http.StatusNotFound)
+	status, res = do(t, s.v2, http.MethodDelete, fmt.Sprintf("%s/images/%d", path, image.ID), s.admin, nil)
+	expect(t, "delete image", status, res, http.StatusOK)
+
+	status, res = do(t, s.v2, http.MethodDelete, path, s.customer, nil)
+	expect(t, "delete as customer", status, res, http.StatusForbidden)
+	status, res = do(t, s.v2, http.MethodDelete, path, s.admin, nil)
+	expect(t, "delete", status, res, http.StatusOK)
+	status, res = do(t, s.v2, http.MethodDelete, path, s.admin, nil)
+	expect(t, "delete again", status, res, http.StatusNotFound)
+}
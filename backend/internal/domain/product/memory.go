@@ -0,0 +1,638 @@
+package product
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/euandresimoes/ecom-go/backend/internal/errs"
+	"github.com/euandresimoes/ecom-go/backend/internal/models"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lucsky/cuid"
+)
+
+// MemoryRepository is a Repository kept in process memory. It enforces the
+// same constraints as the Postgres schema so handlers and services can be
+// tested without a database. Search matches word prefixes on the name only;
+// there is no ranking or typo tolerance.
+type MemoryRepository struct {
+	mu sync.Mutex
+
+	categories map[int]models.CategoryModel
+	products   map[int]models.ProductModel
+	variants   map[int]models.ProductVariantModel
+	images     map[int]models.ProductImageModel
+	nextID     int
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		categories: map[int]models.CategoryModel{},
+		products:   map[int]models.ProductModel{},
+		variants:   map[int]models.ProductVariantModel{},
+		images:     map[int]models.ProductImageModel{},
+		nextID:     1,
+	}
+}
+
+// id hands out identifiers from a single sequence; tests only rely on them
+// being unique. It must be called with mu held.
+func (r *MemoryRepository) id() int {
+	id := r.nextID
+	r.nextID++
+	return id
+}
+
+func now() pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: time.Now(), Valid: true}
+}
+
+func (r *MemoryRepository) CreateCategory(ctx context.Context, data *models.CategoryCreateDto) (models.CategoryModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range r.categories {
+		if c.Name == data.Name {
+			return models.CategoryModel{}, errs.Conflict("category already exists")
+		}
+	}
+
+	id := r.id()
+	c := models.CategoryModel{ID: pgtype.Int4{Int32: int32(id), Valid: true}, Name: data.Name}
+	r.categories[id] = c
+
+	return c, nil
+}
+
+func (r *MemoryRepository) GetAllCategories(ctx context.Context) ([]models.CategoryModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.categories) == 0 {
+		return nil, errs.NotFound("no categories found")
+	}
+
+	cList := make([]models.CategoryModel, 0, len(r.categories))
+	for _, c := range r.categories {
+		cList = append(cList, c)
+	}
+	slices.SortFunc(cList, func(a, b models.CategoryModel) int {
+		return cmp.Compare(a.ID.Int32, b.ID.Int32)
+	})
+
+	return cList, nil
+}
+
+func (r *MemoryRepository) DeleteCategory(ctx context.Context, id int) (models.CategoryModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.categories[id]
+	if !ok {
+		return c, ErrCategoryNotFound
+	}
+
+	for _, p := range r.products {
+		if p.CategoryID == id {
+			return c, errs.Conflict("category still has products")
+		}
+	}
+
+	delete(r.categories, id)
+
+	return c, nil
+}
+
+func (r *MemoryRepository) Create(ctx context.Context, data *models.ProductCreateDto) (models.ProductModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.categories[data.CategoryID]; !ok {
+		return models.ProductModel{}, ErrCategoryNotFound
+	}
+
+	id := r.id()
+	p := models.ProductModel{
+		ID:          pgtype.Int4{Int32: int32(id), Valid: true},
+		PublicID:    cuid.New(),
+		Name:        data.Name,
+		Price:       data.Price,
+		Stock:       data.Stock,
+		CategoryID:  data.CategoryID,
+		WeightUnit:  data.WeightUnit,
+		WeightValue: data.WeightValue,
+		Images:      slices.Clone(data.Images),
+		CreatedAt:   now(),
+		UpdatedAt:   now(),
+	}
+	r.products[id] = p
+
+	return p, nil
+}
+
+func (r *MemoryRepository) Update(ctx context.Context, id int, data *models.ProductUpdateDto) (models.ProductModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ok := r.products[id]
+	if !ok {
+		return p, ErrProductNotFound
+	}
+
+	if data.CategoryID != nil {
+		if _, ok := r.categories[*data.CategoryID]; !ok {
+			return models.ProductModel{}, ErrCategoryNotFound
+		}
+		p.CategoryID = *data.CategoryID
+	}
+	if data.Name != nil {
+		p.Name = *data.Name
+	}
+	if data.Price != nil {
+		p.Price = *data.Price
+	}
+	if data.Stock != nil {
+		p.Stock = *data.Stock
+	}
+	if data.WeightUnit != nil {
+		p.WeightUnit = *data.WeightUnit
+	}
+	if data.WeightValue != nil {
+		p.WeightValue = *data.WeightValue
+	}
+	if data.Images != nil {
+		p.Images = slices.Clone(*data.Images)
+	}
+	p.UpdatedAt = now()
+	r.products[id] = p
+
+	return p, nil
+}
+
+func (r *MemoryRepository) Delete(ctx context.Context, id int) (models.ProductModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ok := r.products[id]
+	if !ok {
+		return p, ErrProductNotFound
+	}
+
+	delete(r.products, id)
+	for vid, v := range r.variants {
+		if v.ProductID == id {
+			delete(r.variants, vid)
+		}
+	}
+	for iid, i := range r.images {
+		if i.ProductID == id {
+			delete(r.images, iid)
+		}
+	}
+
+	return p, nil
+}
+
+func numericFloat(n pgtype.Numeric) float64 {
+	f, _ := n.Float64Value()
+	return f.Float64
+}
+
+// compareSort orders two products by the sort column, then by id, the same
+// way the Postgres ORDER BY does.
+func compareSort(sort string, a, b models.ProductModel) int {
+	var c int
+
+	switch sort {
+	case "price":
+		c = cmp.Compare(numericFloat(a.Price), numericFloat(b.Price))
+	case "name":
+		c = strings.Compare(a.Name, b.Name)
+	case "created_at":
+		c = a.CreatedAt.Time.Compare(b.CreatedAt.Time)
+	}
+
+	if c != 0 {
+		return c
+	}
+
+	return cmp.Compare(a.ID.Int32, b.ID.Int32)
+}
+
+// cursorProduct rebuilds just enough of a product from a cursor to compare
+// other products against it.
+func cursorProduct(sort string, c productCursor) (models.ProductModel, error) {
+	p := models.ProductModel{ID: pgtype.Int4{Int32: c.ID, Valid: true}}
+
+	switch sort {
+	case "price":
+		if err := p.Price.Scan(c.Value); err != nil {
+			return p, errs.BadRequest("invalid cursor")
+		}
+	case "name":
+		p.Name = c.Value
+	case "created_at":
+		t, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return p, errs.BadRequest("invalid cursor")
+		}
+		p.CreatedAt = pgtype.Timestamptz{Time: t, Valid: true}
+	}
+
+	return p, nil
+}
+
+func (r *MemoryRepository) GetAll(ctx context.Context, q *models.ProductListQuery) (models.ProductListModel, error) {
+	list := models.ProductListModel{
+		Products: []models.ProductModel{},
+		Limit:    q.Limit,
+		Page:     q.Page,
+	}
+
+	if _, ok := sortColumns[q.Sort]; !ok {
+		return list, errs.Validation("invalid sort")
+	}
+
+	var minPrice, maxPrice *float64
+	if q.MinPrice != nil {
+		f, err := strconv.ParseFloat(*q.MinPrice, 64)
+		if err != nil {
+			return list, errs.Validation("invalid min_price")
+		}
+		minPrice = &f
+	}
+	if q.MaxPrice != nil {
+		f, err := strconv.ParseFloat(*q.MaxPrice, 64)
+		if err != nil {
+			return list, errs.Validation("invalid max_price")
+		}
+		maxPrice = &f
+	}
+
+	r.mu.Lock()
+	var matched []models.ProductModel
+	for _, p := range r.products {
+		switch {
+		case q.CategoryID != nil && p.CategoryID != *q.CategoryID:
+		case minPrice != nil && numericFloat(p.Price) < *minPrice:
+		case maxPrice != nil && numericFloat(p.Price) > *maxPrice:
+		case q.InStock && p.Stock <= 0:
+		default:
+			matched = append(matched, p)
+		}
+	}
+	r.mu.Unlock()
+
+	list.Total = len(matched)
+
+	desc := q.Order == "desc"
+	slices.SortFunc(matched, func(a, b models.ProductModel) int {
+		if desc {
+			return compareSort(q.Sort, b, a)
+		}
+		return compareSort(q.Sort, a, b)
+	})
+
+	switch {
+	case q.Page > 0:
+		offset := min((q.Page-1)*q.Limit, len(matched))
+		matched = matched[offset:]
+	case q.Cursor != "":
+		c, err := decodeCursor(q.Cursor, q.Sort)
+		if err != nil {
+			return list, err
+		}
+		after, err := cursorProduct(q.Sort, c)
+		if err != nil {
+			return list, err
+		}
+
+		matched = slices.DeleteFunc(matched, func(p models.ProductModel) bool {
+			c := compareSort(q.Sort, p, after)
+			return (!desc && c <= 0) || (desc && c >= 0)
+		})
+	}
+
+	if len(matched) > q.Limit {
+		list.Products = matched[:q.Limit]
+
+		if q.Page == 0 {
+			next, err := encodeCursor(q.Sort, list.Products[q.Limit-1])
+			if err != nil {
+				return list, err
+			}
+			list.NextCursor = &next
+		}
+	} else if len(matched) > 0 {
+		list.Products = matched
+	}
+
+	return list, nil
+}
+
+// withVariants must be called with mu held.
+func (r *MemoryRepository) withVariants(p models.ProductModel) models.ProductModel {
+	p.Variants = r.variantsOf(int(p.ID.Int32))
+	return p
+}
+
+func (r *MemoryRepository) GetByID(ctx context.Context, id int) (models.ProductModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ok := r.products[id]
+	if !ok {
+		return p, ErrProductNotFound
+	}
+
+	return r.withVariants(p), nil
+}
+
+func (r *MemoryRepository) GetByPublicID(ctx context.Context, publicID string) (models.ProductModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, p := range r.products {
+		if p.PublicID == publicID {
+			return r.withVariants(p), nil
+		}
+	}
+
+	return models.ProductModel{}, ErrProductNotFound
+}
+
+func (r *MemoryRepository) Search(ctx context.Context, text string, limit int) ([]models.ProductSearchResultModel, error) {
+	results := []models.ProductSearchResultModel{}
+
+	tsquery := prefixQuery(text)
+	if tsquery == "" {
+		return nil, errs.Validation("invalid search query")
+	}
+
+	var terms []string
+	for _, t := range strings.Split(tsquery, " & ") {
+		terms = append(terms, strings.TrimSuffix(t, ":*"))
+	}
+
+	r.mu.Lock()
+	for _, p := range r.products {
+		words := strings.Fields(strings.ToLower(p.Name))
+
+		all := true
+		for _, t := range terms {
+			if !slices.ContainsFunc(words, func(w string) bool { return strings.HasPrefix(w, t) }) {
+				all = false
+				break
+			}
+		}
+
+		if all {
+			results = append(results, models.ProductSearchResultModel{ProductModel: p, Rank: 1, Highlight: p.Name})
+		}
+	}
+	r.mu.Unlock()
+
+	slices.SortFunc(results, func(a, b models.ProductSearchResultModel) int {
+		return cmp.Compare(a.ID.Int32, b.ID.Int32)
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+// variantsOf must be called with mu held.
+func (r *MemoryRepository) variantsOf(productID int) []models.ProductVariantModel {
+	variants := []models.ProductVariantModel{}
+	for _, v := range r.variants {
+		if v.ProductID == productID {
+			variants = append(variants, v)
+		}
+	}
+	slices.SortFunc(variants, func(a, b models.ProductVariantModel) int {
+		return cmp.Compare(a.ID.Int32, b.ID.Int32)
+	})
+
+	return variants
+}
+
+func (r *MemoryRepository) GetVariants(ctx context.Context, productID int) ([]models.ProductVariantModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.variantsOf(productID), nil
+}
+
+// skuTaken must be called with mu held.
+func (r *MemoryRepository) skuTaken(sku string, except int) bool {
+	for id, v := range r.variants {
+		if v.SKU == sku && id != except {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *MemoryRepository) CreateVariant(ctx context.Context, productID int, data *models.ProductVariantCreateDto) (models.ProductVariantModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.products[productID]; !ok {
+		return models.ProductVariantModel{}, ErrProductNotFound
+	}
+
+	if r.skuTaken(data.SKU, 0) {
+		return models.ProductVariantModel{}, errs.Conflict("sku already in use")
+	}
+
+	options := data.Options
+	if options == nil {
+		options = map[string]string{}
+	}
+
+	id := r.id()
+	v := models.ProductVariantModel{
+		ID:          pgtype.Int4{Int32: int32(id), Valid: true},
+		ProductID:   productID,
+		SKU:         data.SKU,
+		Options:     options,
+		Price:       data.Price,
+		Stock:       data.Stock,
+		WeightUnit:  data.WeightUnit,
+		WeightValue: data.WeightValue,
+		CreatedAt:   now(),
+		UpdatedAt:   now(),
+	}
+	r.variants[id] = v
+
+	return v, nil
+}
+
+func (r *MemoryRepository) UpdateVariant(ctx context.Context, productID int, id int, data *models.ProductVariantUpdateDto) (models.ProductVariantModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v, ok := r.variants[id]
+	if !ok || v.ProductID != productID {
+		return v, ErrVariantNotFound
+	}
+
+	if data.SKU != nil {
+		if r.skuTaken(*data.SKU, id) {
+			return models.ProductVariantModel{}, errs.Conflict("sku already in use")
+		}
+		v.SKU = *data.SKU
+	}
+	if data.Options != nil {
+		v.Options = *data.Options
+	}
+	if data.Price != nil {
+		v.Price = *data.Price
+	}
+	if data.Stock != nil {
+		v.Stock = *data.Stock
+	}
+	if data.WeightUnit != nil {
+		v.WeightUnit = *data.WeightUnit
+	}
+	if data.WeightValue != nil {
+		v.WeightValue = *data.WeightValue
+	}
+	v.UpdatedAt = now()
+	r.variants[id] = v
+
+	return v, nil
+}
+
+func (r *MemoryRepository) DeleteVariant(ctx context.Context, productID int, id int) (models.ProductVariantModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v, ok := r.variants[id]
+	if !ok || v.ProductID != productID {
+		return v, ErrVariantNotFound
+	}
+
+	delete(r.variants, id)
+
+	return v, nil
+}
+
+// imagesOf must be called with mu held.
+func (r *MemoryRepository) imagesOf(productID int) []models.ProductImageModel {
+	images := []models.ProductImageModel{}
+	for _, i := range r.images {
+		if i.ProductID == productID {
+			images = append(images, i)
+		}
+	}
+	slices.SortFunc(images, func(a, b models.ProductImageModel) int {
+		return cmp.Or(cmp.Compare(a.Position, b.Position), cmp.Compare(a.ID, b.ID))
+	})
+
+	return images
+}
+
+// syncImages mirrors the Postgres syncImages. It must be called with mu held.
+func (r *MemoryRepository) syncImages(productID int, removed []string) {
+	p := r.products[productID]
+
+	var uploaded []string
+	for _, i := range r.imagesOf(productID) {
+		uploaded = append(uploaded, i.URL)
+	}
+
+	images := slices.Clone(uploaded)
+	for _, u := range p.Images {
+		if !slices.Contains(uploaded, u) && !slices.Contains(removed, u) {
+			images = append(images, u)
+		}
+	}
+	if images == nil {
+		images = []string{}
+	}
+
+	p.Images = images
+	p.UpdatedAt = now()
+	r.products[productID] = p
+}
+
+func (r *MemoryRepository) GetImages(ctx context.Context, productID int) ([]models.ProductImageModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.imagesOf(productID), nil
+}
+
+func (r *MemoryRepository) AddImage(ctx context.Context, data *models.ProductImageModel) (models.ProductImageModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.products[data.ProductID]; !ok {
+		return models.ProductImageModel{}, ErrProductNotFound
+	}
+
+	position := 0
+	for _, i := range r.imagesOf(data.ProductID) {
+		position = max(position, i.Position+1)
+	}
+
+	i := *data
+	i.ID = r.id()
+	i.Position = position
+	i.CreatedAt = now()
+	r.images[i.ID] = i
+
+	r.syncImages(data.ProductID, nil)
+
+	return i, nil
+}
+
+func (r *MemoryRepository) DeleteImage(ctx context.Context, productID int, id int) (models.ProductImageModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, ok := r.images[id]
+	if !ok || i.ProductID != productID {
+		return models.ProductImageModel{}, ErrImageNotFound
+	}
+
+	delete(r.images, id)
+	r.syncImages(productID, []string{i.URL})
+
+	return i, nil
+}
+
+func (r *MemoryRepository) ReorderImages(ctx context.Context, productID int, ids []int) ([]models.ProductImageModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := r.imagesOf(productID)
+
+	seen := map[int]bool{}
+	for _, id := range ids {
+		i, ok := r.images[id]
+		if !ok || i.ProductID != productID || seen[id] {
+			return nil, errs.Validation("ids must list every image of the product exactly once")
+		}
+		seen[id] = true
+	}
+	if len(ids) != len(current) {
+		return nil, errs.Validation("ids must list every image of the product exactly once")
+	}
+
+	for n, id := range ids {
+		i := r.images[id]
+		i.Position = n
+		r.images[id] = i
+	}
+	r.syncImages(productID, nil)
+
+	return r.imagesOf(productID), nil
+}
@@ -2,27 +2,73 @@ package product
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode"
 
+	"github.com/euandresimoes/ecom-go/backend/internal/errs"
 	"github.com/euandresimoes/ecom-go/backend/internal/infra/cache"
 	"github.com/euandresimoes/ecom-go/backend/internal/models"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/lucsky/cuid"
-	"github.com/redis/go-redis/v9"
 )
 
-type Repository struct {
-	db    *pgxpool.Pool
-	redis *redis.Client
+var (
+	ErrProductNotFound  = errs.NotFound("product not found")
+	ErrCategoryNotFound = errs.NotFound("category not found")
+	ErrVariantNotFound  = errs.NotFound("variant not found")
+	ErrImageNotFound    = errs.NotFound("image not found")
+)
+
+// Repository stores categories, products, variants and image metadata.
+// PostgresRepository is the production implementation; MemoryRepository backs
+// the tests.
+type Repository interface {
+	CreateCategory(ctx context.Context, data *models.CategoryCreateDto) (models.CategoryModel, error)
+	GetAllCategories(ctx context.Context) ([]models.CategoryModel, error)
+	DeleteCategory(ctx context.Context, id int) (models.CategoryModel, error)
+
+	Create(ctx context.Context, data *models.ProductCreateDto) (models.ProductModel, error)
+	Update(ctx context.Context, id int, data *models.ProductUpdateDto) (models.ProductModel, error)
+	Delete(ctx context.Context, id int) (models.ProductModel, error)
+	GetAll(ctx context.Context, q *models.ProductListQuery) (models.ProductListModel, error)
+	GetByID(ctx context.Context, id int) (models.ProductModel, error)
+	GetByPublicID(ctx context.Context, publicID string) (models.ProductModel, error)
+	Search(ctx context.Context, text string, limit int) ([]models.ProductSearchResultModel, error)
+
+	GetVariants(ctx context.Context, productID int) ([]models.ProductVariantModel, error)
+	CreateVariant(ctx context.Context, productID int, data *models.ProductVariantCreateDto) (models.ProductVariantModel, error)
+	UpdateVariant(ctx context.Context, productID int, id int, data *models.ProductVariantUpdateDto) (models.ProductVariantModel, error)
+	DeleteVariant(ctx context.Context, productID int, id int) (models.ProductVariantModel, error)
+
+	GetImages(ctx context.Context, productID int) ([]models.ProductImageModel, error)
+	AddImage(ctx context.Context, data *models.ProductImageModel) (models.ProductImageModel, error)
+	DeleteImage(ctx context.Context, productID int, id int) (models.ProductImageModel, error)
+	ReorderImages(ctx context.Context, productID int, ids []int) ([]models.ProductImageModel, error)
 }
 
-func NewRepository(db *pgxpool.Pool, redis *redis.Client) *Repository {
-	return &Repository{db: db, redis: redis}
+// PostgresRepository keeps the catalog in Postgres and caches reads in the
+// cache store.
+type PostgresRepository struct {
+	db     *pgxpool.Pool
+	cache  cache.Store
+	logger *slog.Logger
 }
 
-func (r *Repository) CreateCategory(data *models.CategoryCreateDto) (models.CategoryModel, error) {
+func NewPostgresRepository(db *pgxpool.Pool, cache cache.Store, logger *slog.Logger) *PostgresRepository {
+	return &PostgresRepository{db: db, cache: cache, logger: logger}
+}
+
+func (r *PostgresRepository) CreateCategory(ctx context.Context, data *models.CategoryCreateDto) (models.CategoryModel, error) {
 	var c models.CategoryModel
 
 	query := `
@@ -32,7 +78,7 @@ func (r *Repository) CreateCategory(data *models.CategoryCreateDto) (models.Cate
 		RETURNING id, name
 	`
 	err := r.db.QueryRow(
-		context.Background(),
+		ctx,
 		query,
 		data.Name,
 	).Scan(
@@ -40,20 +86,22 @@ func (r *Repository) CreateCategory(data *models.CategoryCreateDto) (models.Cate
 		&c.Name,
 	)
 	if err != nil {
-		return c, err
+		return c, categoryError(err)
 	}
 
 	redisKey := "products:categories"
-	cache.DeleteUnique(r.redis, redisKey)
+	if err := cache.DeleteUnique(ctx, r.cache, redisKey); err != nil {
+		r.logger.WarnContext(ctx, "failed to invalidate cache", "key", redisKey, "error", err)
+	}
 
 	return c, nil
 }
 
-func (r *Repository) GetAllCategories() ([]models.CategoryModel, error) {
+func (r *PostgresRepository) GetAllCategories(ctx context.Context) ([]models.CategoryModel, error) {
 	var cList []models.CategoryModel
 
 	redisKey := "products:categories"
-	cachedCategories, err := cache.Get[[]models.CategoryModel](r.redis, redisKey)
+	cachedCategories, err := cache.Get[[]models.CategoryModel](ctx, r.cache, redisKey)
 	if cachedCategories != nil {
 		return *cachedCategories, err
 	}
@@ -64,12 +112,12 @@ func (r *Repository) GetAllCategories() ([]models.CategoryModel, error) {
 		FROM categories
 	`
 	rows, err := r.db.Query(
-		context.Background(),
+		ctx,
 		query,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, errors.New("no categories found")
+			return nil, errs.NotFound("no categories found")
 		}
 
 		return nil, err
@@ -90,15 +138,17 @@ func (r *Repository) GetAllCategories() ([]models.CategoryModel, error) {
 	}
 
 	if len(cList) == 0 {
-		return nil, errors.New("no categories found")
+		return nil, errs.NotFound("no categories found")
 	}
 
-	cache.Set(r.redis, redisKey, &cList)
+	if err := cache.Set(ctx, r.cache, redisKey, &cList); err != nil {
+		r.logger.WarnContext(ctx, "failed to cache categories", "key", redisKey, "error", err)
+	}
 
 	return cList, nil
 }
 
-func (r *Repository) DeleteCategory(id int) (models.CategoryModel, error) {
+func (r *PostgresRepository) DeleteCategory(ctx context.Context, id int) (models.CategoryModel, error) {
 	var c models.CategoryModel
 
 	query := `
@@ -107,7 +157,7 @@ func (r *Repository) DeleteCategory(id int) (models.CategoryModel, error) {
 		RETURNING id, name
 	`
 	err := r.db.QueryRow(
-		context.Background(),
+		ctx,
 		query,
 		id,
 	).Scan(
@@ -116,19 +166,21 @@ func (r *Repository) DeleteCategory(id int) (models.CategoryModel, error) {
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return c, errors.New("category not found")
+			return c, ErrCategoryNotFound
 		}
 
-		return c, err
+		return c, categoryError(err)
 	}
 
 	redisKey := "products:categories"
-	cache.DeleteUnique(r.redis, redisKey)
+	if err := cache.DeleteUnique(ctx, r.cache, redisKey); err != nil {
+		r.logger.WarnContext(ctx, "failed to invalidate cache", "key", redisKey, "error", err)
+	}
 
 	return c, nil
 }
 
-func (r *Repository) Create(data *models.ProductCreateDto) (models.ProductModel, error) {
+func (r *PostgresRepository) Create(ctx context.Context, data *models.ProductCreateDto) (models.ProductModel, error) {
 	var p models.ProductModel
 
 	publicID := cuid.New()
@@ -139,7 +191,7 @@ func (r *Repository) Create(data *models.ProductCreateDto) (models.ProductModel,
 		RETURNING id, public_id, name, price, stock, category_id, weight_unit, weight_value, images, created_at, updated_at
 	`
 	err := r.db.QueryRow(
-		context.Background(),
+		ctx,
 		query,
 		publicID, data.Name, data.Price, data.Stock, data.CategoryID, data.WeightUnit, data.WeightValue, data.Images,
 	).Scan(
@@ -156,19 +208,18 @@ func (r *Repository) Create(data *models.ProductCreateDto) (models.ProductModel,
 		&p.UpdatedAt,
 	)
 	if err != nil {
-		return p, err
+		return p, productError(err)
 	}
 
 	redisKey := "products:*"
-	err = cache.DeleteMany(r.redis, redisKey)
-	if err != nil {
-		return p, err
+	if err := cache.DeleteMany(ctx, r.cache, redisKey); err != nil {
+		r.logger.WarnContext(ctx, "failed to invalidate cache", "key", redisKey, "error", err)
 	}
 
 	return p, nil
 }
 
-func (r *Repository) Delete(id int) (models.ProductModel, error) {
+func (r *PostgresRepository) Delete(ctx context.Context, id int) (models.ProductModel, error) {
 	var p models.ProductModel
 
 	query := `
@@ -177,7 +228,7 @@ func (r *Repository) Delete(id int) (models.ProductModel, error) {
 		RETURNING id, public_id, name, price, stock, category_id, weight_unit, weight_value, images, created_at, updated_at
 	`
 	err := r.db.QueryRow(
-		context.Background(),
+		ctx,
 		query,
 		id,
 	).Scan(
@@ -195,19 +246,21 @@ func (r *Repository) Delete(id int) (models.ProductModel, error) {
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return p, errors.New("product not found")
+			return p, ErrProductNotFound
 		}
 
 		return p, err
 	}
 
 	redisKey := "products:*"
-	cache.DeleteUnique(r.redis, redisKey)
+	if err := cache.DeleteMany(ctx, r.cache, redisKey); err != nil {
+		r.logger.WarnContext(ctx, "failed to invalidate cache", "key", redisKey, "error", err)
+	}
 
 	return p, nil
 }
 
-func (r *Repository) Update(id int, data *models.ProductUpdateDto) (models.ProductModel, error) {
+func (r *PostgresRepository) Update(ctx context.Context, id int, data *models.ProductUpdateDto) (models.ProductModel, error) {
 	var p models.ProductModel
 
 	query := `
@@ -225,7 +278,7 @@ func (r *Repository) Update(id int, data *models.ProductUpdateDto) (models.Produ
 		RETURNING id, public_id, name, price, stock, category_id, weight_unit, weight_value, images, created_at, updated_at
 	`
 	err := r.db.QueryRow(
-		context.Background(),
+		ctx,
 		query,
 		id, data.Name, data.Price, data.Stock, data.CategoryID, data.WeightUnit, data.WeightValue, data.Images,
 	).Scan(
@@ -243,36 +296,201 @@ func (r *Repository) Update(id int, data *models.ProductUpdateDto) (models.Produ
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return p, errors.New("product not found")
+			return p, ErrProductNotFound
 		}
-		return p, err
+		return p, productError(err)
 	}
 
 	redisKey := "products:*"
-	cache.DeleteUnique(r.redis, redisKey)
+	if err := cache.DeleteMany(ctx, r.cache, redisKey); err != nil {
+		r.logger.WarnContext(ctx, "failed to invalidate cache", "key", redisKey, "error", err)
+	}
 
 	return p, nil
 }
 
-func (r *Repository) GetAll() ([]models.ProductModel, error) {
-	var products []models.ProductModel
+// sortColumns whitelists the columns products can be sorted by, along with
+// the type cursor values are cast to when paging through them.
+var sortColumns = map[string]string{
+	"price":      "numeric",
+	"name":       "text",
+	"created_at": "timestamptz",
+}
+
+// productCursor is the position after the last product of a page. Sort
+// records the column Value came from, since a value is only meaningful, and
+// castable, for the sort it was taken from.
+type productCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int32  `json:"id"`
+}
+
+func encodeCursor(sort string, p models.ProductModel) (string, error) {
+	c := productCursor{Sort: sort, ID: p.ID.Int32}
+
+	switch sort {
+	case "price":
+		v, err := p.Price.Value()
+		if err != nil {
+			return "", err
+		}
+		c.Value, _ = v.(string)
+	case "name":
+		c.Value = p.Name
+	case "created_at":
+		c.Value = p.CreatedAt.Time.Format(time.RFC3339Nano)
+	}
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeCursor parses a cursor made by encodeCursor and checks it belongs to a
+// listing sorted by sort, so its value can be cast to that column's type.
+func decodeCursor(cursor string, sort string) (productCursor, error) {
+	var c productCursor
+
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return c, errs.BadRequest("invalid cursor")
+	}
+
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, errs.BadRequest("invalid cursor")
+	}
+
+	if c.Sort != sort {
+		return c, errs.BadRequest("cursor does not match sort")
+	}
+
+	switch sort {
+	case "price":
+		var n pgtype.Numeric
+		if err := n.Scan(c.Value); err != nil {
+			return c, errs.BadRequest("invalid cursor")
+		}
+	case "created_at":
+		if _, err := time.Parse(time.RFC3339Nano, c.Value); err != nil {
+			return c, errs.BadRequest("invalid cursor")
+		}
+	}
+
+	return c, nil
+}
 
-	redisKey := "products:all"
-	cachedProducts, _ := cache.Get[[]models.ProductModel](r.redis, redisKey)
-	if cachedProducts != nil {
-		return *cachedProducts, nil
+// GetAll lists products matching the query. A page number switches to offset
+// pagination; otherwise results are keyset-paginated on (sort column, id) and
+// next_cursor points after the last row returned. Each distinct query is
+// cached under its own key.
+func (r *PostgresRepository) GetAll(ctx context.Context, q *models.ProductListQuery) (models.ProductListModel, error) {
+	list := models.ProductListModel{
+		Products: []models.ProductModel{},
+		Limit:    q.Limit,
+		Page:     q.Page,
+	}
+
+	castType, ok := sortColumns[q.Sort]
+	if !ok {
+		return list, errs.Validation("invalid sort")
+	}
+
+	rawKey, err := json.Marshal(q)
+	if err != nil {
+		return list, err
+	}
+	sum := sha256.Sum256(rawKey)
+	redisKey := fmt.Sprintf("products:list:%x", sum[:16])
+
+	cachedList, _ := cache.Get[models.ProductListModel](ctx, r.cache, redisKey)
+	if cachedList != nil {
+		return *cachedList, nil
+	}
+
+	var (
+		conds []string
+		args  []any
+	)
+	add := func(cond string, v any) {
+		args = append(args, v)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if q.CategoryID != nil {
+		add("category_id = $%d", *q.CategoryID)
+	}
+	if q.MinPrice != nil {
+		add("price >= $%d::numeric", *q.MinPrice)
+	}
+	if q.MaxPrice != nil {
+		add("price <= $%d::numeric", *q.MaxPrice)
+	}
+	if q.InStock {
+		conds = append(conds, "stock > 0")
+	}
+
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
 	}
 
 	query := `
+		SELECT COUNT(*)
+		FROM products
+	` + where
+	err = r.db.QueryRow(
+		ctx,
+		query,
+		args...,
+	).Scan(&list.Total)
+	if err != nil {
+		return list, err
+	}
+
+	dir, op := "ASC", ">"
+	if q.Order == "desc" {
+		dir, op = "DESC", "<"
+	}
+
+	if q.Page == 0 && q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor, q.Sort)
+		if err != nil {
+			return list, err
+		}
+
+		args = append(args, c.Value, c.ID)
+		conds = append(conds, fmt.Sprintf(
+			"(%s, id) %s ($%d::%s, $%d)",
+			q.Sort, op, len(args)-1, castType, len(args),
+		))
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+
+	args = append(args, q.Limit+1)
+	pagination := fmt.Sprintf("LIMIT $%d", len(args))
+	if q.Page > 0 {
+		args = append(args, (q.Page-1)*q.Limit)
+		pagination += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	query = fmt.Sprintf(`
 		SELECT id, public_id, name, price, stock, category_id, weight_unit, weight_value, images, created_at, updated_at
 		FROM products
-	`
+		%s
+		ORDER BY %s %s, id %s
+		%s
+	`, where, q.Sort, dir, dir, pagination)
 	rows, err := r.db.Query(
-		context.Background(),
+		ctx,
 		query,
+		args...,
 	)
 	if err != nil {
-		return nil, err
+		return list, err
 	}
 	defer rows.Close()
 
@@ -292,29 +510,39 @@ func (r *Repository) GetAll() ([]models.ProductModel, error) {
 			&p.UpdatedAt,
 		)
 		if err != nil {
-			return nil, err
+			return list, err
 		}
 
-		products = append(products, p)
+		list.Products = append(list.Products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return list, err
 	}
 
-	if len(products) == 0 {
-		return nil, errors.New("no products found")
+	if len(list.Products) > q.Limit {
+		list.Products = list.Products[:q.Limit]
+
+		if q.Page == 0 {
+			next, err := encodeCursor(q.Sort, list.Products[q.Limit-1])
+			if err != nil {
+				return list, err
+			}
+			list.NextCursor = &next
+		}
 	}
 
-	err = cache.Set(r.redis, redisKey, &products)
-	if err != nil {
-		return nil, err
+	if err := cache.Set(ctx, r.cache, redisKey, &list); err != nil {
+		r.logger.WarnContext(ctx, "failed to cache product list", "key", redisKey, "error", err)
 	}
 
-	return products, nil
+	return list, nil
 }
 
-func (r *Repository) GetByID(id int) (models.ProductModel, error) {
+func (r *PostgresRepository) GetByID(ctx context.Context, id int) (models.ProductModel, error) {
 	var p models.ProductModel
 
 	redisKey := fmt.Sprintf("products:id:%v", id)
-	cachedProduct, _ := cache.Get[models.ProductModel](r.redis, redisKey)
+	cachedProduct, _ := cache.Get[models.ProductModel](ctx, r.cache, redisKey)
 	if cachedProduct != nil {
 		return *cachedProduct, nil
 	}
@@ -325,7 +553,7 @@ func (r *Repository) GetByID(id int) (models.ProductModel, error) {
 		WHERE id = $1
 	`
 	err := r.db.QueryRow(
-		context.Background(),
+		ctx,
 		query,
 		id,
 	).Scan(
@@ -343,25 +571,29 @@ func (r *Repository) GetByID(id int) (models.ProductModel, error) {
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return p, errors.New("product not found")
+			return p, ErrProductNotFound
 		}
 
 		return p, err
 	}
 
-	err = cache.Set(r.redis, redisKey, &p)
+	p.Variants, err = r.GetVariants(ctx, int(p.ID.Int32))
 	if err != nil {
 		return p, err
 	}
 
+	if err := cache.Set(ctx, r.cache, redisKey, &p); err != nil {
+		r.logger.WarnContext(ctx, "failed to cache product", "key", redisKey, "error", err)
+	}
+
 	return p, nil
 }
 
-func (r *Repository) GetByPublicID(publicID string) (models.ProductModel, error) {
+func (r *PostgresRepository) GetByPublicID(ctx context.Context, publicID string) (models.ProductModel, error) {
 	var p models.ProductModel
 
 	redisKey := fmt.Sprintf("products:public:%v", publicID)
-	cachedProduct, _ := cache.Get[models.ProductModel](r.redis, redisKey)
+	cachedProduct, _ := cache.Get[models.ProductModel](ctx, r.cache, redisKey)
 	if cachedProduct != nil {
 		return *cachedProduct, nil
 	}
@@ -372,7 +604,7 @@ func (r *Repository) GetByPublicID(publicID string) (models.ProductModel, error)
 		WHERE public_id = $1
 	`
 	err := r.db.QueryRow(
-		context.Background(),
+		ctx,
 		query,
 		publicID,
 	).Scan(
@@ -390,16 +622,574 @@ func (r *Repository) GetByPublicID(publicID string) (models.ProductModel, error)
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return p, errors.New("product not found")
+			return p, ErrProductNotFound
 		}
 
 		return p, err
 	}
 
-	err = cache.Set(r.redis, redisKey, &p)
+	p.Variants, err = r.GetVariants(ctx, int(p.ID.Int32))
 	if err != nil {
 		return p, err
 	}
 
+	if err := cache.Set(ctx, r.cache, redisKey, &p); err != nil {
+		r.logger.WarnContext(ctx, "failed to cache product", "key", redisKey, "error", err)
+	}
+
 	return p, nil
 }
+
+// prefixQuery turns free text into a tsquery where every word must match as a
+// prefix, e.g. "dark cof" becomes "dark:* & cof:*". Anything that is not a
+// letter or digit is dropped so user input can never break the tsquery syntax.
+func prefixQuery(text string) string {
+	var terms []string
+
+	for _, word := range strings.Fields(text) {
+		word = strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return unicode.ToLower(r)
+			}
+			return -1
+		}, word)
+
+		if word != "" {
+			terms = append(terms, word+":*")
+		}
+	}
+
+	return strings.Join(terms, " & ")
+}
+
+// Search ranks products by full-text match on search_vector, falling back to
+// trigram similarity on the name so small typos still find results. Matched
+// terms are wrapped in <mark> tags in the highlight.
+func (r *PostgresRepository) Search(ctx context.Context, text string, limit int) ([]models.ProductSearchResultModel, error) {
+	results := []models.ProductSearchResultModel{}
+
+	tsquery := prefixQuery(text)
+	if tsquery == "" {
+		return nil, errs.Validation("invalid search query")
+	}
+
+	redisKey := fmt.Sprintf("products:search:%d:%s", limit, strings.ToLower(text))
+	cachedResults, _ := cache.Get[[]models.ProductSearchResultModel](ctx, r.cache, redisKey)
+	if cachedResults != nil {
+		return *cachedResults, nil
+	}
+
+	query := `
+		SELECT
+		id, public_id, name, price, stock, category_id, weight_unit, weight_value, images, created_at, updated_at,
+		(ts_rank(search_vector, to_tsquery('simple', $1)) + similarity(name, $2))::real AS rank,
+		ts_headline('simple', name, to_tsquery('simple', $1), 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true')
+		FROM products
+		WHERE search_vector @@ to_tsquery('simple', $1) OR name % $2
+		ORDER BY rank DESC, id
+		LIMIT $3
+	`
+	rows, err := r.db.Query(
+		ctx,
+		query,
+		tsquery, text, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.ProductSearchResultModel
+		err := rows.Scan(
+			&p.ID,
+			&p.PublicID,
+			&p.Name,
+			&p.Price,
+			&p.Stock,
+			&p.CategoryID,
+			&p.WeightUnit,
+			&p.WeightValue,
+			&p.Images,
+			&p.CreatedAt,
+			&p.UpdatedAt,
+			&p.Rank,
+			&p.Highlight,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := cache.Set(ctx, r.cache, redisKey, &results); err != nil {
+		r.logger.WarnContext(ctx, "failed to cache search results", "key", redisKey, "error", err)
+	}
+
+	return results, nil
+}
+
+// categoryError maps constraint violations on categories to messages the
+// client can act on.
+func categoryError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505":
+			return errs.Conflict("category already exists")
+		case "23503":
+			return errs.Conflict("category still has products")
+		}
+	}
+
+	return err
+}
+
+// productError maps a missing category on insert or update to a not found
+// error instead of a raw foreign key violation.
+func productError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return ErrCategoryNotFound
+	}
+
+	return err
+}
+
+// variantError maps constraint violations on product_variants to messages
+// the client can act on.
+func variantError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505":
+			return errs.Conflict("sku already in use")
+		case "23503":
+			return ErrProductNotFound
+		}
+	}
+
+	return err
+}
+
+func (r *PostgresRepository) GetVariants(ctx context.Context, productID int) ([]models.ProductVariantModel, error) {
+	variants := []models.ProductVariantModel{}
+
+	query := `
+		SELECT id, product_id, sku, options, price, stock, weight_unit, weight_value, created_at, updated_at
+		FROM product_variants
+		WHERE product_id = $1
+		ORDER BY id
+	`
+	rows, err := r.db.Query(
+		ctx,
+		query,
+		productID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v models.ProductVariantModel
+		err := rows.Scan(
+			&v.ID,
+			&v.ProductID,
+			&v.SKU,
+			&v.Options,
+			&v.Price,
+			&v.Stock,
+			&v.WeightUnit,
+			&v.WeightValue,
+			&v.CreatedAt,
+			&v.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		variants = append(variants, v)
+	}
+
+	return variants, rows.Err()
+}
+
+func (r *PostgresRepository) CreateVariant(ctx context.Context, productID int, data *models.ProductVariantCreateDto) (models.ProductVariantModel, error) {
+	var v models.ProductVariantModel
+
+	options := data.Options
+	if options == nil {
+		options = map[string]string{}
+	}
+
+	query := `
+		INSERT INTO
+		product_variants (product_id, sku, options, price, stock, weight_unit, weight_value)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, product_id, sku, options, price, stock, weight_unit, weight_value, created_at, updated_at
+	`
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		productID, data.SKU, options, data.Price, data.Stock, data.WeightUnit, data.WeightValue,
+	).Scan(
+		&v.ID,
+		&v.ProductID,
+		&v.SKU,
+		&v.Options,
+		&v.Price,
+		&v.Stock,
+		&v.WeightUnit,
+		&v.WeightValue,
+		&v.CreatedAt,
+		&v.UpdatedAt,
+	)
+	if err != nil {
+		return v, variantError(err)
+	}
+
+	redisKey := "products:*"
+	if err := cache.DeleteMany(ctx, r.cache, redisKey); err != nil {
+		r.logger.WarnContext(ctx, "failed to invalidate cache", "key", redisKey, "error", err)
+	}
+
+	return v, nil
+}
+
+// UpdateVariant applies a partial update to a variant of the product. A
+// variant of another product is reported as not found.
+func (r *PostgresRepository) UpdateVariant(ctx context.Context, productID int, id int, data *models.ProductVariantUpdateDto) (models.ProductVariantModel, error) {
+	var v models.ProductVariantModel
+
+	query := `
+		UPDATE product_variants
+		SET
+			sku = COALESCE($2, sku),
+			options = COALESCE($3, options),
+			price = COALESCE($4, price),
+			stock = COALESCE($5, stock),
+			weight_unit = COALESCE($6, weight_unit),
+			weight_value = COALESCE($7, weight_value),
+			updated_at = NOW()
+		WHERE id = $1 AND product_id = $8
+		RETURNING id, product_id, sku, options, price, stock, weight_unit, weight_value, created_at, updated_at
+	`
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		id, data.SKU, data.Options, data.Price, data.Stock, data.WeightUnit, data.WeightValue, productID,
+	).Scan(
+		&v.ID,
+		&v.ProductID,
+		&v.SKU,
+		&v.Options,
+		&v.Price,
+		&v.Stock,
+		&v.WeightUnit,
+		&v.WeightValue,
+		&v.CreatedAt,
+		&v.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return v, ErrVariantNotFound
+		}
+
+		return v, variantError(err)
+	}
+
+	redisKey := "products:*"
+	if err := cache.DeleteMany(ctx, r.cache, redisKey); err != nil {
+		r.logger.WarnContext(ctx, "failed to invalidate cache", "key", redisKey, "error", err)
+	}
+
+	return v, nil
+}
+
+func (r *PostgresRepository) DeleteVariant(ctx context.Context, productID int, id int) (models.ProductVariantModel, error) {
+	var v models.ProductVariantModel
+
+	query := `
+		DELETE FROM product_variants
+		WHERE id = $1 AND product_id = $2
+		RETURNING id, product_id, sku, options, price, stock, weight_unit, weight_value, created_at, updated_at
+	`
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		id, productID,
+	).Scan(
+		&v.ID,
+		&v.ProductID,
+		&v.SKU,
+		&v.Options,
+		&v.Price,
+		&v.Stock,
+		&v.WeightUnit,
+		&v.WeightValue,
+		&v.CreatedAt,
+		&v.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return v, ErrVariantNotFound
+		}
+
+		return v, err
+	}
+
+	redisKey := "products:*"
+	if err := cache.DeleteMany(ctx, r.cache, redisKey); err != nil {
+		r.logger.WarnContext(ctx, "failed to invalidate cache", "key", redisKey, "error", err)
+	}
+
+	return v, nil
+}
+
+// syncImages rebuilds products.images so uploaded images come first, in their
+// position order, followed by any externally hosted URLs the product already
+// had. removed lists URLs of uploads that were just deleted.
+func syncImages(ctx context.Context, tx pgx.Tx, productID int, removed []string) error {
+	if removed == nil {
+		removed = []string{}
+	}
+
+	query := `
+		UPDATE products
+		SET
+			images = ARRAY(
+				SELECT url FROM product_images WHERE product_id = $1 ORDER BY position, id
+			) || ARRAY(
+				SELECT u FROM unnest(COALESCE(images, '{}')) WITH ORDINALITY AS t(u, n)
+				WHERE u NOT IN (SELECT url FROM product_images WHERE product_id = $1)
+				AND u <> ALL($2::text[])
+				ORDER BY n
+			),
+			updated_at = NOW()
+		WHERE id = $1
+	`
+	_, err := tx.Exec(
+		ctx,
+		query,
+		productID, removed,
+	)
+
+	return err
+}
+
+func (r *PostgresRepository) GetImages(ctx context.Context, productID int) ([]models.ProductImageModel, error) {
+	images := []models.ProductImageModel{}
+
+	query := `
+		SELECT id, product_id, storage_key, thumbnail_key, url, thumbnail_url, content_type, size, position, created_at
+		FROM product_images
+		WHERE product_id = $1
+		ORDER BY position, id
+	`
+	rows, err := r.db.Query(
+		ctx,
+		query,
+		productID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var i models.ProductImageModel
+		err := rows.Scan(
+			&i.ID,
+			&i.ProductID,
+			&i.StorageKey,
+			&i.ThumbnailKey,
+			&i.URL,
+			&i.ThumbnailURL,
+			&i.ContentType,
+			&i.Size,
+			&i.Position,
+			&i.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		images = append(images, i)
+	}
+
+	return images, rows.Err()
+}
+
+func (r *PostgresRepository) AddImage(ctx context.Context, data *models.ProductImageModel) (models.ProductImageModel, error) {
+	var i models.ProductImageModel
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return i, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO
+		product_images (product_id, storage_key, thumbnail_key, url, thumbnail_url, content_type, size, position)
+		VALUES ($1, $2, $3, $4, $5, $6, $7,
+			(SELECT COALESCE(MAX(position) + 1, 0) FROM product_images WHERE product_id = $1))
+		RETURNING id, product_id, storage_key, thumbnail_key, url, thumbnail_url, content_type, size, position, created_at
+	`
+	err = tx.QueryRow(
+		ctx,
+		query,
+		data.ProductID, data.StorageKey, data.ThumbnailKey, data.URL, data.ThumbnailURL, data.ContentType, data.Size,
+	).Scan(
+		&i.ID,
+		&i.ProductID,
+		&i.StorageKey,
+		&i.ThumbnailKey,
+		&i.URL,
+		&i.ThumbnailURL,
+		&i.ContentType,
+		&i.Size,
+		&i.Position,
+		&i.CreatedAt,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return i, ErrProductNotFound
+		}
+
+		return i, err
+	}
+
+	if err := syncImages(ctx, tx, data.ProductID, nil); err != nil {
+		return i, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return i, err
+	}
+
+	redisKey := "products:*"
+	if err := cache.DeleteMany(ctx, r.cache, redisKey); err != nil {
+		r.logger.WarnContext(ctx, "failed to invalidate cache", "key", redisKey, "error", err)
+	}
+
+	return i, nil
+}
+
+func (r *PostgresRepository) DeleteImage(ctx context.Context, productID int, id int) (models.ProductImageModel, error) {
+	var i models.ProductImageModel
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return i, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		DELETE FROM product_images
+		WHERE id = $1 AND product_id = $2
+		RETURNING id, product_id, storage_key, thumbnail_key, url, thumbnail_url, content_type, size, position, created_at
+	`
+	err = tx.QueryRow(
+		ctx,
+		query,
+		id, productID,
+	).Scan(
+		&i.ID,
+		&i.ProductID,
+		&i.StorageKey,
+		&i.ThumbnailKey,
+		&i.URL,
+		&i.ThumbnailURL,
+		&i.ContentType,
+		&i.Size,
+		&i.Position,
+		&i.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return i, ErrImageNotFound
+		}
+
+		return i, err
+	}
+
+	if err := syncImages(ctx, tx, productID, []string{i.URL}); err != nil {
+		return i, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return i, err
+	}
+
+	redisKey := "products:*"
+	if err := cache.DeleteMany(ctx, r.cache, redisKey); err != nil {
+		r.logger.WarnContext(ctx, "failed to invalidate cache", "key", redisKey, "error", err)
+	}
+
+	return i, nil
+}
+
+// ReorderImages sets image positions to the order of ids, which must list
+// every image of the product exactly once.
+func (r *PostgresRepository) ReorderImages(ctx context.Context, productID int, ids []int) ([]models.ProductImageModel, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE product_images pi
+		SET position = t.n - 1
+		FROM unnest($2::int[]) WITH ORDINALITY AS t(id, n)
+		WHERE pi.id = t.id AND pi.product_id = $1
+	`
+	tag, err := tx.Exec(
+		ctx,
+		query,
+		productID, ids,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int
+
+	query = `
+		SELECT COUNT(*) FROM product_images WHERE product_id = $1
+	`
+	err = tx.QueryRow(
+		ctx,
+		query,
+		productID,
+	).Scan(&total)
+	if err != nil {
+		return nil, err
+	}
+
+	if int(tag.RowsAffected()) != len(ids) || total != len(ids) {
+		return nil, errs.Validation("ids must list every image of the product exactly once")
+	}
+
+	if err := syncImages(ctx, tx, productID, nil); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	redisKey := "products:*"
+	if err := cache.DeleteMany(ctx, r.cache, redisKey); err != nil {
+		r.logger.WarnContext(ctx, "failed to invalidate cache", "key", redisKey, "error", err)
+	}
+
+	return r.GetImages(ctx, productID)
+}
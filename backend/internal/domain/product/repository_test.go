@@ -0,0 +1,515 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/euandresimoes/ecom-go/backend/internal/errs"
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/cache"
+	"github.com/euandresimoes/ecom-go/backend/internal/models"
+	"github.com/euandresimoes/ecom-go/backend/internal/testenv"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func newPostgresRepository(t *testing.T) *PostgresRepository {
+	t.Helper()
+
+	redis, _ := testenv.Redis(t)
+
+	return NewPostgresRepository(testenv.Postgres(t), cache.NewRedisStore(redis), testenv.Logger(t))
+}
+
+func numeric(t *testing.T, s string) pgtype.Numeric {
+	t.Helper()
+
+	var n pgtype.Numeric
+	if err := n.Scan(s); err != nil {
+		t.Fatal(err)
+	}
+
+	return n
+}
+
+func numericEqual(t *testing.T, n pgtype.Numeric, want string) bool {
+	t.Helper()
+
+	got, err := n.Float64Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return got.Float64 == numericFloat(numeric(t, want))
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func seedCategory(t *testing.T, repo Repository, name string) int {
+	t.Helper()
+
+	c, err := repo.CreateCategory(context.Background(), &models.CategoryCreateDto{Name: name})
+	if err != nil {
+		t.Fatalf("create category: %v", err)
+	}
+
+	return int(c.ID.Int32)
+}
+
+func seedProduct(t *testing.T, repo Repository, categoryID int, name string, price string, stock int) models.ProductModel {
+	t.Helper()
+
+	p, err := repo.Create(context.Background(), &models.ProductCreateDto{
+		Name:        name,
+		Price:       numeric(t, price),
+		Stock:       stock,
+		CategoryID:  categoryID,
+		WeightUnit:  models.ProductUnitG,
+		WeightValue: numeric(t, "250"),
+		Images:      []string{"https://cdn.example.com/" + strings.ReplaceAll(name, " ", "-") + ".jpg"},
+	})
+	if err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+
+	return p
+}
+
+func TestPostgresCategories(t *testing.T) {
+	ctx := context.Background()
+	repo := newPostgresRepository(t)
+
+	if _, err := repo.GetAllCategories(ctx); !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("list empty: got %v, want not found", err)
+	}
+
+	coffee := seedCategory(t, repo, "Coffee")
+
+	if _, err := repo.CreateCategory(ctx, &models.CategoryCreateDto{Name: "Coffee"}); !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("duplicate: got %v, want conflict", err)
+	}
+
+	// cache the list so creating another category has to invalidate it
+	if _, err := repo.GetAllCategories(ctx); err != nil {
+		t.Fatal(err)
+	}
+	tea := seedCategory(t, repo, "Tea")
+
+	categories, err := repo.GetAllCategories(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(categories) != 2 {
+		t.Errorf("list: got %d categories, want 2", len(categories))
+	}
+
+	seedProduct(t, repo, coffee, "Espresso", "9.90", 5)
+
+	if _, err := repo.DeleteCategory(ctx, coffee); !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("delete with products: got %v, want conflict", err)
+	}
+	if _, err := repo.DeleteCategory(ctx, 999); !errors.Is(err, ErrCategoryNotFound) {
+		t.Errorf("delete unknown: got %v, want %v", err, ErrCategoryNotFound)
+	}
+	if _, err := repo.DeleteCategory(ctx, tea); err != nil {
+		t.Errorf("delete: %v", err)
+	}
+}
+
+func TestPostgresUpdate(t *testing.T) {
+	ctx := context.Background()
+	repo := newPostgresRepository(t)
+	coffee := seedCategory(t, repo, "Coffee")
+	tea := seedCategory(t, repo, "Tea")
+	p := seedProduct(t, repo, coffee, "Espresso", "9.90", 5)
+	id := int(p.ID.Int32)
+
+	// cache the product so the update has to invalidate it
+	if _, err := repo.GetByID(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	// only the fields that are set change; COALESCE keeps the rest
+	updated, err := repo.Update(ctx, id, &models.ProductUpdateDto{Stock: ptr(7)})
+	if err != nil {
+		t.Fatalf("update stock: %v", err)
+	}
+	if updated.Stock != 7 || updated.Name != "Espresso" || updated.CategoryID != coffee ||
+		!numericEqual(t, updated.Price, "9.90") || !numericEqual(t, updated.WeightValue, "250") ||
+		updated.WeightUnit != models.ProductUnitG || !slices.Equal(updated.Images, p.Images) {
+		t.Errorf("update stock: got %+v", updated)
+	}
+	if !updated.UpdatedAt.Time.After(p.UpdatedAt.Time) {
+		t.Error("updated_at did not move forward")
+	}
+
+	got, err := repo.GetByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Stock != 7 {
+		t.Errorf("get after update: stock = %d, stale cache?", got.Stock)
+	}
+
+	updated, err = repo.Update(ctx, id, &models.ProductUpdateDto{
+		Name:       ptr("Espresso Blend"),
+		Price:      ptr(numeric(t, "12.50")),
+		CategoryID: ptr(tea),
+		WeightUnit: ptr(models.ProductUnitKG),
+		Images:     ptr([]string{}),
+	})
+	if err != nil {
+		t.Fatalf("update several: %v", err)
+	}
+	if updated.Stock != 7 || updated.Name != "Espresso Blend" || updated.CategoryID != tea ||
+		!numericEqual(t, updated.Price, "12.50") || updated.WeightUnit != models.ProductUnitKG || len(updated.Images) != 0 {
+		t.Errorf("update several: got %+v", updated)
+	}
+
+	if _, err := repo.Update(ctx, id, &models.ProductUpdateDto{CategoryID: ptr(999)}); !errors.Is(err, ErrCategoryNotFound) {
+		t.Errorf("unknown category: got %v, want %v", err, ErrCategoryNotFound)
+	}
+	if _, err := repo.Update(ctx, 999, &models.ProductUpdateDto{Stock: ptr(1)}); !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("unknown product: got %v, want %v", err, ErrProductNotFound)
+	}
+}
+
+func TestPostgresGetAll(t *testing.T) {
+	ctx := context.Background()
+	repo := newPostgresRepository(t)
+	coffee := seedCategory(t, repo, "Coffee")
+	tea := seedCategory(t, repo, "Tea")
+
+	seedProduct(t, repo, coffee, "Arabica", "3.00", 1)
+	seedProduct(t, repo, coffee, "Blend", "1.50", 0)
+	seedProduct(t, repo, tea, "Chai", "10.00", 4)
+	seedProduct(t, repo, tea, "Darjeeling", "2.25", 2)
+	seedProduct(t, repo, coffee, "Espresso", "5.75", 3)
+
+	// walk every page through the cursor; 10.00 sorting after 5.75 shows the
+	// cursor value is compared as a number, not as text
+	var names []string
+	q := models.ProductListQuery{Sort: "price", Order: "asc", Limit: 2}
+	for {
+		list, err := repo.GetAll(ctx, &q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if list.Total != 5 {
+			t.Errorf("total = %d, want 5", list.Total)
+		}
+		for _, p := range list.Products {
+			names = append(names, p.Name)
+		}
+		if list.NextCursor == nil {
+			break
+		}
+		q.Cursor = *list.NextCursor
+	}
+	if want := []string{"Blend", "Darjeeling", "Arabica", "Espresso", "Chai"}; !slices.Equal(names, want) {
+		t.Errorf("price pages: got %v, want %v", names, want)
+	}
+
+	names = nil
+	q = models.ProductListQuery{Sort: "created_at", Order: "desc", Limit: 3}
+	for {
+		list, err := repo.GetAll(ctx, &q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range list.Products {
+			names = append(names, p.Name)
+		}
+		if list.NextCursor == nil {
+			break
+		}
+		q.Cursor = *list.NextCursor
+	}
+	if want := []string{"Espresso", "Darjeeling", "Chai", "Blend", "Arabica"}; !slices.Equal(names, want) {
+		t.Errorf("newest first pages: got %v, want %v", names, want)
+	}
+
+	tests := []struct {
+		name  string
+		q     models.ProductListQuery
+		want  []string
+		total int
+	}{
+		{"offset page", models.ProductListQuery{Sort: "name", Order: "asc", Limit: 2, Page: 2}, []string{"Chai", "Darjeeling"}, 5},
+		{"category", models.ProductListQuery{CategoryID: ptr(tea), Sort: "name", Order: "asc", Limit: 10}, []string{"Chai", "Darjeeling"}, 2},
+		{"price range", models.ProductListQuery{MinPrice: ptr("2"), MaxPrice: ptr("5.75"), Sort: "price", Order: "asc", Limit: 10}, []string{"Darjeeling", "Arabica", "Espresso"}, 3},
+		{"in stock", models.ProductListQuery{CategoryID: ptr(coffee), InStock: true, Sort: "name", Order: "desc", Limit: 10}, []string{"Espresso", "Arabica"}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := repo.GetAll(ctx, &tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+			for _, p := range list.Products {
+				names = append(names, p.Name)
+			}
+			if !slices.Equal(names, tt.want) || list.Total != tt.total {
+				t.Errorf("got %v (total %d), want %v (total %d)", names, list.Total, tt.want, tt.total)
+			}
+		})
+	}
+
+	if _, err := repo.GetAll(ctx, &models.ProductListQuery{Sort: "stock", Order: "asc", Limit: 10}); !errors.Is(err, errs.ErrValidation) {
+		t.Errorf("invalid sort: got %v, want validation error", err)
+	}
+
+	// a name cursor must not reach Postgres as a numeric or timestamptz
+	nameList, err := repo.GetAll(ctx, &models.ProductListQuery{Sort: "name", Order: "asc", Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []models.ProductListQuery{
+		{Sort: "name", Order: "asc", Limit: 10, Cursor: "not-a-cursor"},
+		{Sort: "price", Order: "asc", Limit: 10, Cursor: *nameList.NextCursor},
+		{Sort: "created_at", Order: "asc", Limit: 10, Cursor: *nameList.NextCursor},
+	} {
+		if _, err := repo.GetAll(ctx, &q); !errors.Is(err, errs.ErrBadRequest) {
+			t.Errorf("GetAll(%+v): got %v, want bad request", q, err)
+		}
+	}
+}
+
+func TestPostgresSearch(t *testing.T) {
+	ctx := context.Background()
+	repo := newPostgresRepository(t)
+	coffee := seedCategory(t, repo, "Coffee")
+
+	seedProduct(t, repo, coffee, "Dark Roast Coffee", "9.00", 1)
+	seedProduct(t, repo, coffee, "Espresso Blend", "8.00", 1)
+	seedProduct(t, repo, coffee, "Green Tea", "4.00", 1)
+
+	results, err := repo.Search(ctx, "dark cof", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == 0 || results[0].Name != "Dark Roast Coffee" {
+		t.Fatalf("prefix search: got %+v", results)
+	}
+	if !strings.Contains(results[0].Highlight, "<mark>") {
+		t.Errorf("highlight = %q, want matched terms marked", results[0].Highlight)
+	}
+
+	// no word starts with "espreso"; trigram similarity still finds it
+	results, err = repo.Search(ctx, "espreso", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == 0 || results[0].Name != "Espresso Blend" {
+		t.Errorf("typo search: got %+v", results)
+	}
+
+	if _, err := repo.Search(ctx, "!!!", 10); !errors.Is(err, errs.ErrValidation) {
+		t.Errorf("punctuation only: got %v, want validation error", err)
+	}
+}
+
+// A Redis outage must not fail requests whose database work succeeded: reads
+// skip the cache and writes are not reported as failed after committing.
+func TestPostgresCacheDown(t *testing.T) {
+	ctx := context.Background()
+	redis, server := testenv.Redis(t)
+	repo := NewPostgresRepository(testenv.Postgres(t), cache.NewRedisStore(redis), testenv.Logger(t))
+
+	categoryID := seedCategory(t, repo, "Coffee")
+	server.Close()
+
+	p := seedProduct(t, repo, categoryID, "Espresso Blend", "9.90", 5)
+
+	if _, err := repo.GetAllCategories(ctx); err != nil {
+		t.Errorf("categories: %v", err)
+	}
+	if _, err := repo.GetAll(ctx, &models.ProductListQuery{Sort: "created_at", Order: "desc", Limit: 10}); err != nil {
+		t.Errorf("list: %v", err)
+	}
+	if _, err := repo.GetByID(ctx, int(p.ID.Int32)); err != nil {
+		t.Errorf("by id: %v", err)
+	}
+	if _, err := repo.GetByPublicID(ctx, p.PublicID); err != nil {
+		t.Errorf("by public id: %v", err)
+	}
+	if _, err := repo.Search(ctx, "espresso", 10); err != nil {
+		t.Errorf("search: %v", err)
+	}
+}
+
+func TestPostgresVariants(t *testing.T) {
+	ctx := context.Background()
+	repo := newPostgresRepository(t)
+	coffee := seedCategory(t, repo, "Coffee")
+	p := seedProduct(t, repo, coffee, "Espresso", "9.90", 5)
+	other := seedProduct(t, repo, coffee, "Decaf", "7.50", 5)
+	id := int(p.ID.Int32)
+
+	create := func(productID int, sku string) (models.ProductVariantModel, error) {
+		return repo.CreateVariant(ctx, productID, &models.ProductVariantCreateDto{
+			SKU:         sku,
+			Options:     map[string]string{"grind": "fine", "size": "1kg"},
+			Price:       numeric(t, "20.00"),
+			Stock:       3,
+			WeightUnit:  models.ProductUnitKG,
+			WeightValue: numeric(t, "1"),
+		})
+	}
+
+	v, err := create(id, "ESP-FINE-1KG")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if v.Options["size"] != "1kg" || v.ProductID != id {
+		t.Errorf("create: got %+v", v)
+	}
+
+	if _, err := create(int(other.ID.Int32), "ESP-FINE-1KG"); !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("sku taken: got %v, want conflict", err)
+	}
+	if _, err := create(999, "ESP-X"); !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("unknown product: got %v, want %v", err, ErrProductNotFound)
+	}
+	if _, err := create(id, "ESP-COARSE-1KG"); err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := repo.UpdateVariant(ctx, id, int(v.ID.Int32), &models.ProductVariantUpdateDto{Stock: ptr(9)})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if updated.Stock != 9 || updated.SKU != "ESP-FINE-1KG" || updated.Options["grind"] != "fine" || !numericEqual(t, updated.Price, "20") {
+		t.Errorf("update: got %+v", updated)
+	}
+
+	if _, err := repo.UpdateVariant(ctx, id, int(v.ID.Int32), &models.ProductVariantUpdateDto{SKU: ptr("ESP-COARSE-1KG")}); !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("update to taken sku: got %v, want conflict", err)
+	}
+	if _, err := repo.UpdateVariant(ctx, id, 999, &models.ProductVariantUpdateDto{Stock: ptr(1)}); !errors.Is(err, ErrVariantNotFound) {
+		t.Errorf("update unknown: got %v, want %v", err, ErrVariantNotFound)
+	}
+	if _, err := repo.UpdateVariant(ctx, int(other.ID.Int32), int(v.ID.Int32), &models.ProductVariantUpdateDto{Stock: ptr(1)}); !errors.Is(err, ErrVariantNotFound) {
+		t.Errorf("update through other product: got %v, want %v", err, ErrVariantNotFound)
+	}
+	if _, err := repo.DeleteVariant(ctx, int(other.ID.Int32), int(v.ID.Int32)); !errors.Is(err, ErrVariantNotFound) {
+		t.Errorf("delete through other product: got %v, want %v", err, ErrVariantNotFound)
+	}
+
+	got, err := repo.GetByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Variants) != 2 || got.Variants[0].ID != v.ID {
+		t.Errorf("product variants: got %+v", got.Variants)
+	}
+
+	if _, err := repo.DeleteVariant(ctx, id, int(v.ID.Int32)); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := repo.DeleteVariant(ctx, id, int(v.ID.Int32)); !errors.Is(err, ErrVariantNotFound) {
+		t.Errorf("delete again: got %v, want %v", err, ErrVariantNotFound)
+	}
+
+	// deleting the product takes its variants with it
+	if _, err := repo.Delete(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	variants, err := repo.GetVariants(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(variants) != 0 {
+		t.Errorf("variants after product delete: got %d, want 0", len(variants))
+	}
+}
+
+func TestPostgresImages(t *testing.T) {
+	ctx := context.Background()
+	repo := newPostgresRepository(t)
+	coffee := seedCategory(t, repo, "Coffee")
+	p := seedProduct(t, repo, coffee, "Espresso", "9.90", 5)
+	id := int(p.ID.Int32)
+	external := p.Images[0]
+
+	add := func(productID int, n int) (models.ProductImageModel, error) {
+		return repo.AddImage(ctx, &models.ProductImageModel{
+			ProductID:    productID,
+			StorageKey:   fmt.Sprintf("products/%d/%d.png", productID, n),
+			ThumbnailKey: fmt.Sprintf("products/%d/%d_thumb.png", productID, n),
+			URL:          fmt.Sprintf("/uploads/products/%d/%d.png", productID, n),
+			ThumbnailURL: fmt.Sprintf("/uploads/products/%d/%d_thumb.png", productID, n),
+			ContentType:  "image/png",
+			Size:         100,
+		})
+	}
+
+	images := func() []string {
+		t.Helper()
+
+		got, err := repo.GetByID(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return got.Images
+	}
+
+	first, err := add(id, 1)
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	second, err := add(id, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Position != 0 || second.Position != 1 {
+		t.Errorf("positions = %d, %d, want 0, 1", first.Position, second.Position)
+	}
+
+	if _, err := add(999, 1); !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("unknown product: got %v, want %v", err, ErrProductNotFound)
+	}
+
+	// uploads come first, in position order, then external URLs
+	if got, want := images(), []string{first.URL, second.URL, external}; !slices.Equal(got, want) {
+		t.Errorf("after add: got %v, want %v", got, want)
+	}
+
+	for _, ids := range [][]int{
+		{second.ID},
+		{second.ID, first.ID, 999},
+	} {
+		if _, err := repo.ReorderImages(ctx, id, ids); !errors.Is(err, errs.ErrValidation) {
+			t.Errorf("reorder %v: got %v, want validation error", ids, err)
+		}
+	}
+
+	reordered, err := repo.ReorderImages(ctx, id, []int{second.ID, first.ID})
+	if err != nil {
+		t.Fatalf("reorder: %v", err)
+	}
+	if reordered[0].ID != second.ID || reordered[0].Position != 0 || reordered[1].Position != 1 {
+		t.Errorf("reorder: got %+v", reordered)
+	}
+	if got, want := images(), []string{second.URL, first.URL, external}; !slices.Equal(got, want) {
+		t.Errorf("after reorder: got %v, want %v", got, want)
+	}
+
+	if _, err := repo.DeleteImage(ctx, id, second.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := repo.DeleteImage(ctx, id, second.ID); !errors.Is(err, ErrImageNotFound) {
+		t.Errorf("delete again: got %v, want %v", err, ErrImageNotFound)
+	}
+	if got, want := images(), []string{first.URL, external}; !slices.Equal(got, want) {
+		t.Errorf("after delete: got %v, want %v", got, want)
+	}
+}
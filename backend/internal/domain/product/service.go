@@ -1,47 +1,171 @@
 package product
 
-import "github.com/euandresimoes/ecom-go/backend/internal/models"
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/euandresimoes/ecom-go/backend/internal/errs"
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/imaging"
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/storage"
+	"github.com/euandresimoes/ecom-go/backend/internal/models"
+	"github.com/lucsky/cuid"
+)
+
+const thumbnailSize = 320
 
 type Service struct {
-	repo *Repository
+	repo    Repository
+	storage storage.Storage
+	logger  *slog.Logger
+}
+
+func NewService(repo Repository, storage storage.Storage, logger *slog.Logger) *Service {
+	return &Service{repo: repo, storage: storage, logger: logger}
+}
+
+func (s *Service) CreateCategory(ctx context.Context, data *models.CategoryCreateDto) (models.CategoryModel, error) {
+	return s.repo.CreateCategory(ctx, data)
+}
+
+func (s *Service) GetAllCategories(ctx context.Context) ([]models.CategoryModel, error) {
+	return s.repo.GetAllCategories(ctx)
+}
+
+func (s *Service) DeleteCategory(ctx context.Context, id int) (models.CategoryModel, error) {
+	return s.repo.DeleteCategory(ctx, id)
 }
 
-func NewService(repo *Repository) *Service {
-	return &Service{repo: repo}
+func (s *Service) Create(ctx context.Context, data *models.ProductCreateDto) (models.ProductModel, error) {
+	return s.repo.Create(ctx, data)
 }
 
-func (s *Service) CreateCategory(data *models.CategoryCreateDto) (models.CategoryModel, error) {
-	return s.repo.CreateCategory(data)
+func (s *Service) Delete(ctx context.Context, id int) (models.ProductModel, error) {
+	images, err := s.repo.GetImages(ctx, id)
+	if err != nil {
+		return models.ProductModel{}, err
+	}
+
+	p, err := s.repo.Delete(ctx, id)
+	if err != nil {
+		return p, err
+	}
+
+	for _, i := range images {
+		s.removeObjects(ctx, i.StorageKey, i.ThumbnailKey)
+	}
+
+	return p, nil
 }
 
-func (s *Service) GetAllCategories() ([]models.CategoryModel, error) {
-	return s.repo.GetAllCategories()
+func (s *Service) Update(ctx context.Context, id int, data *models.ProductUpdateDto) (models.ProductModel, error) {
+	return s.repo.Update(ctx, id, data)
 }
 
-func (s *Service) DeleteCategory(id int) (models.CategoryModel, error) {
-	return s.repo.DeleteCategory(id)
+func (s *Service) GetAll(ctx context.Context, q *models.ProductListQuery) (models.ProductListModel, error) {
+	return s.repo.GetAll(ctx, q)
 }
 
-func (s *Service) Create(data *models.ProductCreateDto) (models.ProductModel, error) {
-	return s.repo.Create(data)
+func (s *Service) GetByID(ctx context.Context, id int) (models.ProductModel, error) {
+	return s.repo.GetByID(ctx, id)
 }
 
-func (s *Service) Delete(id int) (models.ProductModel, error) {
-	return s.repo.Delete(id)
+func (s *Service) GetByPublicID(ctx context.Context, publicID string) (models.ProductModel, error) {
+	return s.repo.GetByPublicID(ctx, publicID)
 }
 
-func (s *Service) Update(id int, data *models.ProductUpdateDto) (models.ProductModel, error) {
-	return s.repo.Update(id, data)
+func (s *Service) Search(ctx context.Context, text string, limit int) ([]models.ProductSearchResultModel, error) {
+	return s.repo.Search(ctx, text, limit)
 }
 
-func (s *Service) GetAll() ([]models.ProductModel, error) {
-	return s.repo.GetAll()
+func (s *Service) GetVariants(ctx context.Context, productID int) ([]models.ProductVariantModel, error) {
+	return s.repo.GetVariants(ctx, productID)
 }
 
-func (s *Service) GetByID(id int) (models.ProductModel, error) {
-	return s.repo.GetByID(id)
+func (s *Service) CreateVariant(ctx context.Context, productID int, data *models.ProductVariantCreateDto) (models.ProductVariantModel, error) {
+	return s.repo.CreateVariant(ctx, productID, data)
 }
 
-func (s *Service) GetByPublicID(publicID string) (models.ProductModel, error) {
-	return s.repo.GetByPublicID(publicID)
+func (s *Service) UpdateVariant(ctx context.Context, productID int, id int, data *models.ProductVariantUpdateDto) (models.ProductVariantModel, error) {
+	return s.repo.UpdateVariant(ctx, productID, id, data)
+}
+
+func (s *Service) DeleteVariant(ctx context.Context, productID int, id int) (models.ProductVariantModel, error) {
+	return s.repo.DeleteVariant(ctx, productID, id)
+}
+
+func (s *Service) GetImages(ctx context.Context, productID int) ([]models.ProductImageModel, error) {
+	return s.repo.GetImages(ctx, productID)
+}
+
+// UploadImage validates the file by its content, stores it together with a
+// generated thumbnail and appends it to the product's images.
+func (s *Service) UploadImage(ctx context.Context, productID int, data []byte) (models.ProductImageModel, error) {
+	contentType, ext, err := imaging.Sniff(data)
+	if err != nil {
+		return models.ProductImageModel{}, errs.Unsupported(err.Error())
+	}
+
+	thumb, thumbType, thumbExt, err := imaging.Thumbnail(data, contentType, thumbnailSize)
+	if err != nil {
+		return models.ProductImageModel{}, errs.Validation("invalid image: " + err.Error())
+	}
+
+	name := cuid.New()
+
+	image := models.ProductImageModel{
+		ProductID:    productID,
+		StorageKey:   fmt.Sprintf("products/%d/%s%s", productID, name, ext),
+		ThumbnailKey: fmt.Sprintf("products/%d/%s_thumb%s", productID, name, thumbExt),
+		ContentType:  contentType,
+		Size:         len(data),
+	}
+
+	image.URL, err = s.storage.Put(ctx, image.StorageKey, bytes.NewReader(data), int64(len(data)), contentType)
+	if err != nil {
+		return image, err
+	}
+
+	image.ThumbnailURL, err = s.storage.Put(ctx, image.ThumbnailKey, bytes.NewReader(thumb), int64(len(thumb)), thumbType)
+	if err != nil {
+		s.removeObjects(ctx, image.StorageKey)
+		return image, err
+	}
+
+	created, err := s.repo.AddImage(ctx, &image)
+	if err != nil {
+		s.removeObjects(ctx, image.StorageKey, image.ThumbnailKey)
+		return created, err
+	}
+
+	return created, nil
+}
+
+func (s *Service) DeleteImage(ctx context.Context, productID int, id int) (models.ProductImageModel, error) {
+	image, err := s.repo.DeleteImage(ctx, productID, id)
+	if err != nil {
+		return image, err
+	}
+
+	s.removeObjects(ctx, image.StorageKey, image.ThumbnailKey)
+
+	return image, nil
+}
+
+func (s *Service) ReorderImages(ctx context.Context, productID int, data *models.ProductImageOrderDto) ([]models.ProductImageModel, error) {
+	return s.repo.ReorderImages(ctx, productID, data.IDs)
+}
+
+// removeObjects deletes files whose rows are already gone. Failures only leave
+// orphaned files behind, so they are logged instead of failing the request.
+// The cleanup runs even when the request itself was cancelled.
+func (s *Service) removeObjects(ctx context.Context, keys ...string) {
+	ctx = context.WithoutCancel(ctx)
+
+	for _, key := range keys {
+		if err := s.storage.Delete(ctx, key); err != nil {
+			s.logger.ErrorContext(ctx, "failed to delete object from storage", "key", key, "error", err)
+		}
+	}
 }
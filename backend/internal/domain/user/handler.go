@@ -0,0 +1,154 @@
+package user
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/security"
+	"github.com/euandresimoes/ecom-go/backend/internal/middlewares"
+	"github.com/euandresimoes/ecom-go/backend/internal/models"
+	"github.com/euandresimoes/ecom-go/backend/internal/response"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+type Handler struct {
+	service   *Service
+	validator *validator.Validate
+}
+
+func NewHandler(service *Service, validator *validator.Validate, jwt *security.JWTManager) http.Handler {
+	h := &Handler{service: service, validator: validator}
+
+	r := chi.NewRouter()
+
+	r.Use(middlewares.Admin(jwt))
+
+	r.Get("/", h.GetAll)
+	r.Patch("/role", h.UpdateRole)
+	r.Patch("/disable", h.Disable)
+	r.Patch("/enable", h.Enable)
+	r.Post("/logout", h.ForceLogout)
+
+	return r
+}
+
+func (h *Handler) GetAll(w http.ResponseWriter, r *http.Request) {
+	email := r.URL.Query().Get("email")
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	users, err := h.service.GetAll(r.Context(), email, page, limit)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "users found",
+		"data":    users,
+	})
+}
+
+func (h *Handler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	adminID := int(r.Context().Value(models.UserIDKey).(float64))
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var data models.UserRoleUpdateModel
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := h.validator.Struct(&data); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	user, err := h.service.UpdateRole(r.Context(), adminID, id, &data)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "user role updated",
+		"data":    user,
+	})
+}
+
+func (h *Handler) Disable(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, true)
+}
+
+func (h *Handler) Enable(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, false)
+}
+
+func (h *Handler) setDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	adminID := int(r.Context().Value(models.UserIDKey).(float64))
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	user, err := h.service.SetDisabled(r.Context(), adminID, id, disabled)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	message := "user enabled"
+	if disabled {
+		message = "user disabled"
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": message,
+		"data":    user,
+	})
+}
+
+func (h *Handler) ForceLogout(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		response.Problem(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	err = h.service.ForceLogout(r.Context(), id)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  http.StatusOK,
+		"message": "user sessions revoked",
+	})
+}
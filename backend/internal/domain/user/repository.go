@@ -0,0 +1,230 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/euandresimoes/ecom-go/backend/internal/errs"
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/security"
+	"github.com/euandresimoes/ecom-go/backend/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repository struct {
+	db         *pgxpool.Pool
+	jwtManager *security.JWTManager
+	logger     *slog.Logger
+}
+
+var ErrUserNotFound = errs.NotFound("user not found")
+
+// likeEscaper escapes the LIKE wildcards, using the backslash as ESCAPE
+// character.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func NewRepository(db *pgxpool.Pool, jwtManager *security.JWTManager, logger *slog.Logger) *Repository {
+	return &Repository{db: db, jwtManager: jwtManager, logger: logger}
+}
+
+// GetAll lists users page by page, optionally only those whose email contains
+// the given text. The text is matched literally, % and _ included.
+func (r *Repository) GetAll(ctx context.Context, email string, page int, limit int) (models.UserListModel, error) {
+	list := models.UserListModel{
+		Users: []models.UserAdminModel{},
+		Page:  page,
+		Limit: limit,
+	}
+
+	email = likeEscaper.Replace(email)
+
+	query := `
+		SELECT COUNT(*)
+		FROM users
+		WHERE ($1 = '' OR email ILIKE '%' || $1 || '%' ESCAPE '\')
+	`
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		email,
+	).Scan(&list.Total)
+	if err != nil {
+		return list, err
+	}
+
+	query = `
+		SELECT
+		id, first_name, COALESCE(last_name, ''), email, role,
+		email_verified_at, disabled_at, deleted_at, created_at, updated_at
+		FROM users
+		WHERE ($1 = '' OR email ILIKE '%' || $1 || '%' ESCAPE '\')
+		ORDER BY id
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.Query(
+		ctx,
+		query,
+		email, limit, (page-1)*limit,
+	)
+	if err != nil {
+		return list, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var u models.UserAdminModel
+		err := rows.Scan(
+			&u.ID,
+			&u.FirstName,
+			&u.LastName,
+			&u.Email,
+			&u.Role,
+			&u.EmailVerifiedAt,
+			&u.DisabledAt,
+			&u.DeletedAt,
+			&u.CreatedAt,
+			&u.UpdatedAt,
+		)
+		if err != nil {
+			return list, err
+		}
+
+		list.Users = append(list.Users, u)
+	}
+
+	return list, rows.Err()
+}
+
+// UpdateRole changes the role of a user. Access tokens carry the role, so the
+// ones already issued are revoked; the user picks up the new role on the next
+// refresh. Deleted users are reported as not found.
+func (r *Repository) UpdateRole(ctx context.Context, id int, role models.UserRole) (models.UserAdminModel, error) {
+	var u models.UserAdminModel
+
+	query := `
+		UPDATE users
+		SET role = $2, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING
+		id, first_name, COALESCE(last_name, ''), email, role,
+		email_verified_at, disabled_at, deleted_at, created_at, updated_at
+	`
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		id, role,
+	).Scan(
+		&u.ID,
+		&u.FirstName,
+		&u.LastName,
+		&u.Email,
+		&u.Role,
+		&u.EmailVerifiedAt,
+		&u.DisabledAt,
+		&u.DeletedAt,
+		&u.CreatedAt,
+		&u.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return u, ErrUserNotFound
+		}
+
+		return u, err
+	}
+
+	return u, r.jwtManager.RevokeUser(ctx, id)
+}
+
+// SetDisabled disables or re-enables an account. Disabling also ends every
+// session of the user. Deleted users are reported as not found.
+func (r *Repository) SetDisabled(ctx context.Context, id int, disabled bool) (models.UserAdminModel, error) {
+	var u models.UserAdminModel
+
+	query := `
+		UPDATE users
+		SET
+			disabled_at = CASE WHEN $2 THEN COALESCE(disabled_at, NOW()) ELSE NULL END,
+			updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING
+		id, first_name, COALESCE(last_name, ''), email, role,
+		email_verified_at, disabled_at, deleted_at, created_at, updated_at
+	`
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		id, disabled,
+	).Scan(
+		&u.ID,
+		&u.FirstName,
+		&u.LastName,
+		&u.Email,
+		&u.Role,
+		&u.EmailVerifiedAt,
+		&u.DisabledAt,
+		&u.DeletedAt,
+		&u.CreatedAt,
+		&u.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return u, ErrUserNotFound
+		}
+
+		return u, err
+	}
+
+	if !disabled {
+		return u, r.jwtManager.EnableUser(ctx, id)
+	}
+
+	if err := r.jwtManager.DisableUser(ctx, id); err != nil {
+		return u, err
+	}
+
+	return u, r.RevokeSessions(ctx, id)
+}
+
+// RevokeSessions revokes every refresh token of the user and every access
+// token issued so far.
+func (r *Repository) RevokeSessions(ctx context.Context, id int) error {
+	var exists bool
+
+	query := `
+		SELECT EXISTS
+		(SELECT 1 FROM users WHERE id = $1)
+	`
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		id,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return ErrUserNotFound
+	}
+
+	query = `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`
+	tag, err := r.db.Exec(
+		ctx,
+		query,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	r.logger.DebugContext(ctx, "refresh tokens revoked", "user_id", id, "count", tag.RowsAffected())
+
+	return r.jwtManager.RevokeUser(ctx, id)
+}
@@ -0,0 +1,89 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/euandresimoes/ecom-go/backend/internal/models"
+	"github.com/euandresimoes/ecom-go/backend/internal/testenv"
+)
+
+func TestGetAllEmailFilter(t *testing.T) {
+	ctx := context.Background()
+	db := testenv.Postgres(t)
+	repo := NewRepository(db, nil, testenv.Logger(t))
+
+	for _, email := range []string{"a_b@example.com", "axb@example.com", "100%@example.com", `back\slash@example.com`} {
+		query := `
+			INSERT INTO users (first_name, email, password_hash)
+			VALUES ('Alice', $1, 'x')
+		`
+		if _, err := db.Exec(ctx, query, email); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// wildcards in the filter match only themselves
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{"", []string{"a_b@example.com", "axb@example.com", "100%@example.com", `back\slash@example.com`}},
+		{"a_b", []string{"a_b@example.com"}},
+		{"%", []string{"100%@example.com"}},
+		{`\s`, []string{`back\slash@example.com`}},
+		{"AXB", []string{"axb@example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			list, err := repo.GetAll(ctx, tt.filter, 1, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, u := range list.Users {
+				got = append(got, u.Email)
+			}
+			if !slices.Equal(got, tt.want) || list.Total != len(tt.want) {
+				t.Errorf("got %v (total %d), want %v", got, list.Total, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeletedUserNotFound(t *testing.T) {
+	ctx := context.Background()
+	db := testenv.Postgres(t)
+	repo := NewRepository(db, nil, testenv.Logger(t))
+
+	var id int
+	query := `
+		INSERT INTO users (first_name, email, password_hash, deleted_at)
+		VALUES ('Alice', 'alice@example.com', 'x', NOW())
+		RETURNING id
+	`
+	if err := db.QueryRow(ctx, query).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.UpdateRole(ctx, id, models.RoleAdmin); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("UpdateRole: got %v, want %v", err, ErrUserNotFound)
+	}
+	if _, err := repo.SetDisabled(ctx, id, true); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("SetDisabled: got %v, want %v", err, ErrUserNotFound)
+	}
+
+	var role models.UserRole
+	var disabled bool
+	query = `SELECT role, disabled_at IS NOT NULL FROM users WHERE id = $1`
+	if err := db.QueryRow(ctx, query, id).Scan(&role, &disabled); err != nil {
+		t.Fatal(err)
+	}
+	if role != models.RoleCustomer || disabled {
+		t.Errorf("deleted user was changed: role %s, disabled %t", role, disabled)
+	}
+}
@@ -0,0 +1,64 @@
+package user
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/euandresimoes/ecom-go/backend/internal/errs"
+	"github.com/euandresimoes/ecom-go/backend/internal/models"
+)
+
+var ErrSelfAction = errs.Forbidden("admins cannot change their own account")
+
+type Service struct {
+	repo   *Repository
+	logger *slog.Logger
+}
+
+func NewService(repo *Repository, logger *slog.Logger) *Service {
+	return &Service{repo: repo, logger: logger}
+}
+
+func (s *Service) GetAll(ctx context.Context, email string, page int, limit int) (models.UserListModel, error) {
+	return s.repo.GetAll(ctx, email, page, limit)
+}
+
+func (s *Service) UpdateRole(ctx context.Context, adminID int, id int, data *models.UserRoleUpdateModel) (models.UserAdminModel, error) {
+	if adminID == id {
+		return models.UserAdminModel{}, ErrSelfAction
+	}
+
+	u, err := s.repo.UpdateRole(ctx, id, data.Role)
+	if err != nil {
+		return u, err
+	}
+
+	s.logger.InfoContext(ctx, "user role changed", "user_id", id, "role", data.Role, "admin_id", adminID)
+
+	return u, nil
+}
+
+func (s *Service) SetDisabled(ctx context.Context, adminID int, id int, disabled bool) (models.UserAdminModel, error) {
+	if adminID == id {
+		return models.UserAdminModel{}, ErrSelfAction
+	}
+
+	u, err := s.repo.SetDisabled(ctx, id, disabled)
+	if err != nil {
+		return u, err
+	}
+
+	s.logger.InfoContext(ctx, "user access changed", "user_id", id, "disabled", disabled, "admin_id", adminID)
+
+	return u, nil
+}
+
+func (s *Service) ForceLogout(ctx context.Context, id int) error {
+	if err := s.repo.RevokeSessions(ctx, id); err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "user sessions revoked", "user_id", id)
+
+	return nil
+}
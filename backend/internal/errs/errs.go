@@ -0,0 +1,62 @@
+// Package errs holds the kinds of failure the domain layer reports. Each kind
+// is a sentinel that typed errors unwrap to, so handlers can pick a status
+// code with errors.Is while the message stays specific to the case.
+package errs
+
+import "errors"
+
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrValidation   = errors.New("validation failed")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrUnsupported  = errors.New("unsupported media type")
+)
+
+// Error is a domain error of a given kind. Message is safe to show to
+// clients. Unexpected failures are not wrapped in an Error: they are returned
+// as they are and answered with a bare 500.
+type Error struct {
+	Kind    error
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// BadRequest reports a request the server cannot interpret at all, as opposed
+// to Validation, which reports well-formed input with invalid values.
+func BadRequest(message string) error {
+	return &Error{Kind: ErrBadRequest, Message: message}
+}
+
+func NotFound(message string) error {
+	return &Error{Kind: ErrNotFound, Message: message}
+}
+
+func Conflict(message string) error {
+	return &Error{Kind: ErrConflict, Message: message}
+}
+
+func Validation(message string) error {
+	return &Error{Kind: ErrValidation, Message: message}
+}
+
+func Unauthorized(message string) error {
+	return &Error{Kind: ErrUnauthorized, Message: message}
+}
+
+func Forbidden(message string) error {
+	return &Error{Kind: ErrForbidden, Message: message}
+}
+
+func Unsupported(message string) error {
+	return &Error{Kind: ErrUnsupported, Message: message}
+}
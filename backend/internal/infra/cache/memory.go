@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a Store kept in process memory, for tests and for running
+// without Redis. Expired entries are dropped when they are read.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[string]memoryEntry{}}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, ErrMiss
+	}
+
+	if !e.expiresAt.IsZero() && time.Now().After(e.expiresAt) {
+		delete(s.entries, key)
+		return nil, ErrMiss
+	}
+
+	return e.value, nil
+}
+
+// Set stores a copy of value. A ttl of zero keeps it until deleted, as in
+// Redis.
+func (s *MemoryStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := memoryEntry{value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		e.expiresAt = time.Now().Add(ttl)
+	}
+	s.entries[key] = e
+
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.entries, key)
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) DeleteMatching(ctx context.Context, pattern string) error {
+	re := globRegexp(pattern)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key := range s.entries {
+		if re.MatchString(key) {
+			delete(s.entries, key)
+		}
+	}
+
+	return nil
+}
+
+// globRegexp translates the * and ? wildcards of a Redis MATCH pattern.
+// Unlike path.Match, * also spans "/", which shows up in cached search terms.
+func globRegexp(pattern string) *regexp.Regexp {
+	var b strings.Builder
+
+	b.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+
+	return regexp.MustCompile(b.String())
+}
@@ -3,20 +3,50 @@ package cache
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"time"
 
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/metrics"
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 )
 
-func NewRedis(url string, pwd string) (*redis.Client, error) {
+// TTL is how long Set keeps values.
+var TTL = 30 * time.Minute
+
+// ErrMiss is returned by Store.Get for keys that are absent or expired.
+var ErrMiss = errors.New("cache miss")
+
+// Store is where the helpers below keep JSON values. RedisStore backs it in
+// production; MemoryStore stands in where Redis is not available.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+	// DeleteMatching removes every key matching a glob pattern such as
+	// "products:*".
+	DeleteMatching(ctx context.Context, pattern string) error
+}
+
+// NewRedis connects to Redis. timeout bounds every command on its own; the
+// caller's context deadline applies as well when it is shorter.
+func NewRedis(url string, pwd string, poolSize int, timeout time.Duration) (*redis.Client, error) {
 	r := redis.NewClient(&redis.Options{
 		Addr:     url,
 		Password: pwd,
 		DB:       0,
 		Protocol: 2,
+		PoolSize: poolSize,
+
+		ReadTimeout:           timeout,
+		WriteTimeout:          timeout,
+		ContextTimeoutEnabled: true,
 	})
 
+	if err := redisotel.InstrumentTracing(r); err != nil {
+		return nil, err
+	}
+
 	ctx := context.Background()
 
 	err := r.Set(
@@ -29,28 +59,82 @@ func NewRedis(url string, pwd string) (*redis.Client, error) {
 		return nil, err
 	}
 
-	val, err := r.Get(
+	err = r.Get(
 		ctx,
 		"redis_test",
-	).Result()
+	).Err()
 	if err != nil {
 		return nil, err
 	}
 
-	fmt.Println("redis working?", val)
-
 	return r, nil
 }
 
-func Get[T any](r *redis.Client, key string) (*T, error) {
-	val, err := r.Get(context.Background(), key).Result()
+// RedisStore is a Store on top of a Redis client.
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) ([]byte, error) {
+	val, err := s.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrMiss
+	}
+
+	return val, err
+}
+
+func (s *RedisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (s *RedisStore) Delete(ctx context.Context, keys ...string) error {
+	return s.client.Del(ctx, keys...).Err()
+}
+
+// DeleteMatching walks the whole keyspace with SCAN rather than stopping at
+// the first batch.
+func (s *RedisStore) DeleteMatching(ctx context.Context, pattern string) error {
+	var cursor uint64
+
+	for {
+		keys, next, err := s.client.Scan(
+			ctx,
+			cursor,
+			pattern,
+			100,
+		).Result()
+		if err != nil {
+			return err
+		}
+
+		if len(keys) > 0 {
+			if err := s.client.Del(ctx, keys...).Err(); err != nil {
+				return err
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+func Get[T any](ctx context.Context, s Store, key string) (*T, error) {
+	val, err := s.Get(ctx, key)
+	metrics.CacheRead(key, err == nil)
 	if err != nil {
 		return nil, err
 	}
 
 	out := new(T)
 
-	err = json.Unmarshal([]byte(val), out)
+	err = json.Unmarshal(val, out)
 	if err != nil {
 		return nil, err
 	}
@@ -58,33 +142,23 @@ func Get[T any](r *redis.Client, key string) (*T, error) {
 	return out, err
 }
 
-func Set(r *redis.Client, key string, data any) error {
+func Set(ctx context.Context, s Store, key string, data any) error {
 	bytes, err := json.Marshal(data)
 	if err != nil {
 		return err
 	}
 
-	return r.Set(context.Background(), key, bytes, time.Minute*30).Err()
+	return s.Set(ctx, key, bytes, TTL)
 }
 
-func DeleteMany(r *redis.Client, key string) error {
-	keys, _, err := r.Scan(
-		context.Background(),
-		uint64(0),
-		key,
-		100,
-	).Result()
-	if err != nil {
-		return err
-	}
-
-	if len(keys) > 0 {
-		r.Del(context.Background(), keys...)
-	}
-
-	return nil
+// DeleteMany removes every key matching the pattern. Invalidation follows
+// writes that are already committed, so it is not cut short when the request
+// is cancelled.
+func DeleteMany(ctx context.Context, s Store, pattern string) error {
+	return s.DeleteMatching(context.WithoutCancel(ctx), pattern)
 }
 
-func DeleteUnique(r *redis.Client, key string) {
-	r.Del(context.Background(), key)
+// DeleteUnique removes a single key, likewise surviving cancellation.
+func DeleteUnique(ctx context.Context, s Store, key string) error {
+	return s.Delete(context.WithoutCancel(ctx), key)
 }
@@ -3,18 +3,29 @@ package database
 import (
 	"context"
 	"errors"
+	"strconv"
+	"time"
 
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/tracing"
 	"github.com/euandresimoes/ecom-go/backend/internal/models"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func NewPostgres(url string) (*pgxpool.Pool, error) {
+// NewPostgres opens the pool. statementTimeout is set as the session's
+// statement_timeout, so Postgres cancels any single query running longer even
+// when the caller's context has no deadline.
+func NewPostgres(url string, maxConns int, minConns int, statementTimeout time.Duration) (*pgxpool.Pool, error) {
 	config, err := pgxpool.ParseConfig(url)
 	if err != nil {
 		return nil, err
 	}
 
+	config.MaxConns = int32(maxConns)
+	config.MinConns = int32(minConns)
+	config.ConnConfig.Tracer = tracing.QueryTracer{}
+	config.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(statementTimeout.Milliseconds(), 10)
+
 	pool, err := pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
 		return nil, err
@@ -23,7 +34,7 @@ func NewPostgres(url string) (*pgxpool.Pool, error) {
 	return pool, nil
 }
 
-func CreateAdmin(adminEmail string, adminPwd string, db *pgxpool.Pool) error {
+func CreateAdmin(adminEmail string, adminPwd string, bcryptCost int, db *pgxpool.Pool) error {
 	var count int
 
 	query := `
@@ -47,15 +58,15 @@ func CreateAdmin(adminEmail string, adminPwd string, db *pgxpool.Pool) error {
 		return errors.New("ADMIN_PASSWORD env not set")
 	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(adminPwd), 10)
+	hash, err := bcrypt.GenerateFromPassword([]byte(adminPwd), bcryptCost)
 	if err != nil {
 		return err
 	}
 
 	query = `
 		INSERT INTO users
-		(first_name, last_name, email, password_hash, role)
-		VALUES ($1, $2, $3, $4, $5)
+		(first_name, last_name, email, password_hash, role, email_verified_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
 	`
 	_, err = db.Exec(
 		context.Background(),
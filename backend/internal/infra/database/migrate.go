@@ -0,0 +1,38 @@
+package database
+
+import (
+	"embed"
+	"io/fs"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// NewMigrator returns a goose provider for the migrations embedded in the
+// binary. It records versions in goose_db_version like the goose CLI does, so
+// databases migrated either way stay in sync, and holds a Postgres advisory
+// lock while migrating so replicas starting together take turns. Closing the
+// provider leaves the pool open.
+func NewMigrator(pool *pgxpool.Pool) (*goose.Provider, error) {
+	fsys, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, err
+	}
+
+	return goose.NewProvider(
+		goose.DialectPostgres,
+		stdlib.OpenDBFromPool(pool),
+		fsys,
+		goose.WithSessionLocker(locker),
+	)
+}
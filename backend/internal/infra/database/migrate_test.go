@@ -0,0 +1,83 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/database"
+	"github.com/euandresimoes/ecom-go/backend/internal/testenv"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestMigrations(t *testing.T) {
+	ctx := context.Background()
+	db := testenv.EmptyPostgres(t)
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer migrator.Close()
+
+	sources := migrator.ListSources()
+	if len(sources) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	latest := sources[len(sources)-1].Version
+
+	results, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if len(results) != len(sources) {
+		t.Errorf("up applied %d migrations, want %d", len(results), len(sources))
+	}
+
+	results, err = migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("second up: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("second up applied %d migrations, want 0", len(results))
+	}
+
+	// every Down section must undo its Up cleanly, or rollbacks in
+	// production would fail halfway
+	if _, err := migrator.DownTo(ctx, 0); err != nil {
+		t.Fatalf("down to 0: %v", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("up after down: %v", err)
+	}
+
+	version, err := migrator.GetDBVersion(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != latest {
+		t.Errorf("version = %d, want %d", version, latest)
+	}
+}
+
+func TestCreateAdmin(t *testing.T) {
+	ctx := context.Background()
+	db := testenv.Postgres(t)
+
+	if err := database.CreateAdmin("admin@example.com", "", bcrypt.MinCost, db); err == nil {
+		t.Error("CreateAdmin without a password succeeded")
+	}
+
+	for range 2 {
+		if err := database.CreateAdmin("admin@example.com", "password123", bcrypt.MinCost, db); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var count int
+	if err := db.QueryRow(ctx, "SELECT COUNT(*) FROM users WHERE role = 'admin'").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("got %d admins, want 1", count)
+	}
+}
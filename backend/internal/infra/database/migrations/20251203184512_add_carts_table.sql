@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE
+IF NOT EXISTS
+carts (
+    id SERIAL PRIMARY KEY,
+    user_id INT REFERENCES users(id) ON DELETE CASCADE NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE
+IF NOT EXISTS
+cart_items (
+    id SERIAL PRIMARY KEY,
+    cart_id INT REFERENCES carts(id) ON DELETE CASCADE NOT NULL,
+    product_id INT REFERENCES products(id) ON DELETE CASCADE NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (cart_id, product_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS cart_items;
+DROP TABLE IF EXISTS carts;
+-- +goose StatementEnd
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE
+IF NOT EXISTS
+orders (
+    id SERIAL PRIMARY KEY,
+    public_id VARCHAR(100) NOT NULL UNIQUE,
+    user_id INT REFERENCES users(id) NOT NULL,
+    total DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE
+IF NOT EXISTS
+order_items (
+    id SERIAL PRIMARY KEY,
+    order_id INT REFERENCES orders(id) ON DELETE CASCADE NOT NULL,
+    product_id INT REFERENCES products(id) ON DELETE SET NULL,
+    product_name VARCHAR(30) NOT NULL,
+    unit_price DECIMAL(10, 2) NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    line_total DECIMAL(10, 2) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS orders_user_id_idx ON orders (user_id);
+CREATE INDEX IF NOT EXISTS order_items_order_id_idx ON order_items (order_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS order_items;
+DROP TABLE IF EXISTS orders;
+-- +goose StatementEnd
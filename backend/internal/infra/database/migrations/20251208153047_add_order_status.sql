@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TYPE order_status
+AS ENUM
+('pending', 'paid', 'shipped', 'delivered', 'cancelled', 'refunded');
+
+ALTER TABLE orders
+ADD COLUMN IF NOT EXISTS status order_status NOT NULL DEFAULT 'pending';
+
+CREATE TABLE
+IF NOT EXISTS
+order_status_history (
+    id SERIAL PRIMARY KEY,
+    order_id INT REFERENCES orders(id) ON DELETE CASCADE NOT NULL,
+    from_status order_status,
+    to_status order_status NOT NULL,
+    changed_by INT REFERENCES users(id) ON DELETE SET NULL,
+    note TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS orders_status_idx ON orders (status);
+CREATE INDEX IF NOT EXISTS order_status_history_order_id_idx ON order_status_history (order_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS order_status_history;
+ALTER TABLE orders DROP COLUMN IF EXISTS status;
+DROP TYPE IF EXISTS order_status;
+-- +goose StatementEnd
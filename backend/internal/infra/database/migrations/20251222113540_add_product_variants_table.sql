@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE
+IF NOT EXISTS
+product_variants (
+    id SERIAL PRIMARY KEY,
+    product_id INT REFERENCES products(id) ON DELETE CASCADE NOT NULL,
+    sku VARCHAR(64) NOT NULL UNIQUE,
+    options JSONB NOT NULL DEFAULT '{}',
+    price DECIMAL(10, 2) NOT NULL,
+    stock INT NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    weight_unit weight_unit NOT NULL,
+    weight_value DECIMAL(10, 2) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS product_variants_product_id_idx ON product_variants (product_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS product_variants;
+-- +goose StatementEnd
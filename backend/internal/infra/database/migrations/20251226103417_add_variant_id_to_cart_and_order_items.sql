@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE cart_items
+ADD COLUMN IF NOT EXISTS variant_id INT REFERENCES product_variants(id) ON DELETE CASCADE;
+
+ALTER TABLE cart_items DROP CONSTRAINT IF EXISTS cart_items_cart_id_product_id_key;
+ALTER TABLE cart_items
+ADD CONSTRAINT cart_items_cart_id_product_id_variant_id_key
+UNIQUE NULLS NOT DISTINCT (cart_id, product_id, variant_id);
+
+ALTER TABLE order_items
+ADD COLUMN IF NOT EXISTS variant_id INT REFERENCES product_variants(id) ON DELETE SET NULL,
+ADD COLUMN IF NOT EXISTS sku VARCHAR(64);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE order_items
+DROP COLUMN IF EXISTS sku,
+DROP COLUMN IF EXISTS variant_id;
+
+DELETE FROM cart_items WHERE variant_id IS NOT NULL;
+ALTER TABLE cart_items DROP CONSTRAINT IF EXISTS cart_items_cart_id_product_id_variant_id_key;
+ALTER TABLE cart_items DROP COLUMN IF EXISTS variant_id;
+ALTER TABLE cart_items
+ADD CONSTRAINT cart_items_cart_id_product_id_key
+UNIQUE (cart_id, product_id);
+-- +goose StatementEnd
@@ -0,0 +1,88 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/gabriel-vasile/mimetype"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// maxPixels caps decoded image size so a small, highly compressed upload cannot
+// blow up memory when it is decoded for the thumbnail.
+const maxPixels = 40_000_000
+
+var ErrUnsupportedType = errors.New("unsupported image type")
+
+var allowed = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// Sniff detects the content type from the bytes themselves, ignoring whatever
+// the client claimed, and returns it with the file extension to store under.
+func Sniff(data []byte) (string, string, error) {
+	mtype := mimetype.Detect(data)
+
+	for m, ext := range allowed {
+		if mtype.Is(m) {
+			return m, ext, nil
+		}
+	}
+
+	return "", "", ErrUnsupportedType
+}
+
+// Thumbnail scales the image down to fit in a size x size box, keeping the
+// aspect ratio. Images with possible transparency are encoded as PNG, the rest
+// as JPEG. It returns the encoded thumbnail, its content type and extension.
+func Thumbnail(data []byte, contentType string, size int) ([]byte, string, string, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, "", "", errors.New("image dimensions too large")
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(1, h*size/w)
+		} else {
+			w, h = max(1, w*size/h), size
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+
+	var buf bytes.Buffer
+
+	switch contentType {
+	case "image/png", "image/gif", "image/webp":
+		if err := png.Encode(&buf, dst); err != nil {
+			return nil, "", "", err
+		}
+		return buf.Bytes(), "image/png", ".png", nil
+	default:
+		if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+			return nil, "", "", err
+		}
+		return buf.Bytes(), "image/jpeg", ".jpg", nil
+	}
+}
@@ -0,0 +1,147 @@
+// Package logging builds the JSON logger. Records logged with a request
+// context carry the request id and the id of the signed in user, and
+// credentials are redacted wherever they show up in attributes.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const redacted = "[REDACTED]"
+
+// attribute keys, and header names, whose values are never written
+var sensitive = map[string]bool{
+	"authorization": true,
+	"cookie":        true,
+	"set-cookie":    true,
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"secret":        true,
+}
+
+type requestKey struct{}
+
+// request holds what the access log learns while the request goes down the
+// middleware chain, such as the user the auth middlewares resolve.
+type request struct {
+	userID int
+}
+
+// New returns a logger writing JSON lines to w at level and above.
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	h := slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redact,
+	})
+
+	return slog.New(contextHandler{h})
+}
+
+// contextHandler adds request_id and user_id to records logged with a
+// request context.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if id := middleware.GetReqID(ctx); id != "" {
+		rec.AddAttrs(slog.String("request_id", id))
+	}
+	if req, ok := ctx.Value(requestKey{}).(*request); ok && req.userID != 0 {
+		rec.AddAttrs(slog.Int("user_id", req.userID))
+	}
+
+	return h.Handler.Handle(ctx, rec)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+func redact(_ []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	if sensitive[key] || strings.Contains(key, "password") {
+		return slog.String(a.Key, redacted)
+	}
+
+	if header, ok := a.Value.Any().(http.Header); ok {
+		clean := header.Clone()
+		for name := range clean {
+			if sensitive[strings.ToLower(name)] {
+				clean[name] = []string{redacted}
+			}
+		}
+		return slog.Any(a.Key, clean)
+	}
+
+	return a
+}
+
+// SetUserID records the signed in user for the request's log lines. The auth
+// middlewares call it once the token is verified.
+func SetUserID(ctx context.Context, id int) {
+	if req, ok := ctx.Value(requestKey{}).(*request); ok {
+		req.userID = id
+	}
+}
+
+// Requests writes one access log line per request with its route, status and
+// latency. Server errors are logged at error level, client errors at warn and
+// probes and scrapes at debug so they stay out of the way.
+func Requests(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			r = r.WithContext(context.WithValue(r.Context(), requestKey{}, &request{}))
+
+			next.ServeHTTP(ww, r)
+
+			route := "unmatched"
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				if pattern := rctx.RoutePattern(); pattern != "" {
+					route = pattern
+				}
+			}
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			level := slog.LevelInfo
+			switch {
+			case status >= 500:
+				level = slog.LevelError
+			case status >= 400:
+				level = slog.LevelWarn
+			case route == "/livez" || route == "/readyz" || route == "/metrics":
+				level = slog.LevelDebug
+			}
+
+			logger.LogAttrs(r.Context(), level, "request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("route", route),
+				slog.Int("status", status),
+				slog.Int("bytes", ww.BytesWritten()),
+				slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.String("remote_addr", r.RemoteAddr),
+				slog.String("user_agent", r.UserAgent()),
+			)
+		})
+	}
+}
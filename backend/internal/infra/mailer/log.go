@@ -0,0 +1,45 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// LogMailer writes every message to w instead of delivering it. It is meant
+// for local development and tests, where the links inside the emails are
+// read straight from the output. Messages carry live reset and verification
+// tokens, so it must never be used in production.
+type LogMailer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewLog(w io.Writer) *LogMailer {
+	return &LogMailer{w: w}
+}
+
+func NewFile(path string) (*LogMailer, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewLog(f), nil
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := fmt.Fprintf(
+		m.w,
+		"--- %s\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC3339), msg.To, msg.Subject, msg.Body,
+	)
+
+	return err
+}
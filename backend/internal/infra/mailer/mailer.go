@@ -0,0 +1,15 @@
+package mailer
+
+import "context"
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers transactional emails such as password reset links. Send
+// gives up when ctx is done.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
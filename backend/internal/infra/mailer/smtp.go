@@ -0,0 +1,120 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+type SMTPMailer struct {
+	host    string
+	addr    string
+	auth    smtp.Auth
+	from    string
+	timeout time.Duration
+}
+
+// NewSMTP builds a mailer for the given server. timeout bounds a whole
+// delivery, from dialing to QUIT, on top of the deadline of the context passed
+// to Send.
+func NewSMTP(host string, port string, username string, password string, from string, timeout time.Duration) *SMTPMailer {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTPMailer{
+		host:    host,
+		addr:    net.JoinHostPort(host, port),
+		auth:    auth,
+		from:    from,
+		timeout: timeout,
+	}
+}
+
+// Send delivers msg the way smtp.SendMail does, upgrading to TLS when the
+// server offers STARTTLS, but over a connection that is closed as soon as ctx
+// is done or the timeout runs out, so a stalled server cannot hold the
+// request that triggered the email.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	err := m.send(ctx, msg)
+	if err != nil && ctx.Err() != nil {
+		// closing the connection under the client surfaces as an I/O error
+		return ctx.Err()
+	}
+
+	return err
+}
+
+func (m *SMTPMailer) send(ctx context.Context, msg Message) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return err
+		}
+	}
+
+	if m.auth != nil {
+		if ok, _ := c.Extension("AUTH"); ok {
+			if err := c.Auth(m.auth); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := c.Mail(m.from); err != nil {
+		return err
+	}
+	if err := c.Rcpt(msg.To); err != nil {
+		return err
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "From: %s\r\n", m.from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+
+	if _, err := w.Write([]byte(b.String())); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
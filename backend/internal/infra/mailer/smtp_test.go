@@ -0,0 +1,74 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// stalledServer accepts connections and never answers, like an SMTP server
+// that hangs before its greeting.
+func stalledServer(t *testing.T) (host string, port string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, c := range conns {
+				c.Close()
+			}
+		}()
+
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+
+	host, port, err = net.SplitHostPort(ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return host, port
+}
+
+func TestSMTPSendStalledServer(t *testing.T) {
+	host, port := stalledServer(t)
+	msg := Message{To: "alice@example.com", Subject: "Hi", Body: "Hello"}
+
+	t.Run("timeout", func(t *testing.T) {
+		m := NewSMTP(host, port, "", "", "no-reply@example.com", 50*time.Millisecond)
+
+		start := time.Now()
+		err := m.Send(context.Background(), msg)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Send returned after %v", elapsed)
+		}
+	})
+
+	t.Run("request cancelled", func(t *testing.T) {
+		m := NewSMTP(host, port, "", "", "no-reply@example.com", time.Minute)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		if err := m.Send(ctx, msg); !errors.Is(err, context.Canceled) {
+			t.Errorf("got %v, want %v", err, context.Canceled)
+		}
+	})
+}
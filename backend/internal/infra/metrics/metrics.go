@@ -0,0 +1,163 @@
+// Package metrics exposes Prometheus metrics for HTTP traffic, the Postgres
+// pool, the cache and a few business events.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method and route pattern.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	cacheHits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_hits_total",
+		Help: "Cache reads that found a value, by keyspace.",
+	}, []string{"keyspace"})
+
+	cacheMisses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_misses_total",
+		Help: "Cache reads that found nothing, by keyspace.",
+	}, []string{"keyspace"})
+
+	Registrations = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "auth_registrations_total",
+		Help: "Accounts registered.",
+	})
+
+	Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_logins_total",
+		Help: "Login attempts by result (success or failure).",
+	}, []string{"result"})
+
+	OrdersPlaced = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "orders_placed_total",
+		Help: "Orders created at checkout.",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		cacheHits,
+		cacheMisses,
+		Registrations,
+		Logins,
+		OrdersPlaced,
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// HTTP records every request under its chi route pattern, e.g.
+// "/api/v2/products/{publicID}", so ids in the path do not explode the
+// number of series. Requests that match no route are labeled "unmatched".
+func HTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			if pattern := rctx.RoutePattern(); pattern != "" {
+				route = pattern
+			}
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		httpRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		httpDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// CacheRead counts a cache lookup under the first segment of its key, so
+// "products:list:..." is counted as "products".
+func CacheRead(key string, hit bool) {
+	keyspace, _, _ := strings.Cut(key, ":")
+	if hit {
+		cacheHits.WithLabelValues(keyspace).Inc()
+		return
+	}
+
+	cacheMisses.WithLabelValues(keyspace).Inc()
+}
+
+// RegisterPool exports the statistics of the Postgres pool.
+func RegisterPool(pool *pgxpool.Pool) {
+	registry.MustRegister(&poolCollector{pool: pool})
+}
+
+var (
+	poolAcquired = prometheus.NewDesc(
+		"db_pool_acquired_conns", "Connections currently in use.", nil, nil)
+	poolIdle = prometheus.NewDesc(
+		"db_pool_idle_conns", "Idle connections in the pool.", nil, nil)
+	poolTotal = prometheus.NewDesc(
+		"db_pool_total_conns", "Open connections in the pool.", nil, nil)
+	poolMax = prometheus.NewDesc(
+		"db_pool_max_conns", "Maximum size of the pool.", nil, nil)
+	poolAcquires = prometheus.NewDesc(
+		"db_pool_acquires_total", "Successful connection acquires.", nil, nil)
+	poolEmptyAcquires = prometheus.NewDesc(
+		"db_pool_empty_acquires_total", "Acquires that had to wait for a connection.", nil, nil)
+	poolWait = prometheus.NewDesc(
+		"db_pool_acquire_wait_seconds_total", "Time spent waiting for a connection.", nil, nil)
+)
+
+type poolCollector struct {
+	pool *pgxpool.Pool
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolAcquired
+	ch <- poolIdle
+	ch <- poolTotal
+	ch <- poolMax
+	ch <- poolAcquires
+	ch <- poolEmptyAcquires
+	ch <- poolWait
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(poolAcquired, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdle, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolTotal, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolMax, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolEmptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolWait, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+}
@@ -1,30 +1,38 @@
 package security
 
 import (
+	"context"
 	"errors"
+	"math"
 	"time"
 
 	"github.com/euandresimoes/ecom-go/backend/internal/models"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/lucsky/cuid"
 )
 
 type JWTManager struct {
-	secret  string
-	expires time.Duration
+	secret   string
+	expires  time.Duration
+	denylist *Denylist
 }
 
-func NewJWTManager(secret string, expires time.Duration) *JWTManager {
-	return &JWTManager{secret: secret, expires: expires}
+func NewJWTManager(secret string, expires time.Duration, denylist *Denylist) *JWTManager {
+	return &JWTManager{secret: secret, expires: expires, denylist: denylist}
 }
 
 func (j *JWTManager) Sign(id int, role models.UserRole) (string, error) {
+	now := time.Now()
 	token := jwt.NewWithClaims(
 		jwt.SigningMethodHS256,
 		jwt.MapClaims{
+			"jti":  cuid.New(),
 			"id":   id,
 			"role": string(role),
-			"exp":  time.Now().Add(j.expires).Unix(),
-			"iat":  time.Now().Unix(),
+			"exp":  now.Add(j.expires).Unix(),
+			// fractional so IsRevoked can tell a token issued right after
+			// a RevokeUser from one issued in the same second before it
+			"iat": float64(now.UnixMilli()) / 1e3,
 		},
 	)
 
@@ -46,3 +54,106 @@ func (j *JWTManager) Verify(tokenString string) (*jwt.Token, error) {
 
 	return token, nil
 }
+
+// Revoke denylists a single access token until its exp.
+func (j *JWTManager) Revoke(ctx context.Context, claims jwt.MapClaims) error {
+	if j.denylist == nil {
+		return nil
+	}
+
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return errors.New("token has no jti")
+	}
+
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return errors.New("token has no exp")
+	}
+
+	return j.denylist.Revoke(ctx, jti, exp.Time)
+}
+
+// RevokeUser invalidates every access token issued to the user so far.
+func (j *JWTManager) RevokeUser(ctx context.Context, id int) error {
+	if j.denylist == nil {
+		return nil
+	}
+
+	return j.denylist.RevokeUser(ctx, id)
+}
+
+func (j *JWTManager) DisableUser(ctx context.Context, id int) error {
+	if j.denylist == nil {
+		return nil
+	}
+
+	return j.denylist.DisableUser(ctx, id)
+}
+
+func (j *JWTManager) EnableUser(ctx context.Context, id int) error {
+	if j.denylist == nil {
+		return nil
+	}
+
+	return j.denylist.EnableUser(ctx, id)
+}
+
+func (j *JWTManager) IsRevoked(ctx context.Context, claims jwt.MapClaims) (bool, error) {
+	if j.denylist == nil {
+		return false, nil
+	}
+
+	jti, _ := claims["jti"].(string)
+	id, _ := claims["id"].(float64)
+
+	// read directly, GetIssuedAt truncates to jwt.TimePrecision
+	iat, ok := claims["iat"].(float64)
+	if !ok {
+		return true, nil
+	}
+
+	return j.denylist.IsRevoked(ctx, jti, int(id), int64(math.Round(iat*1e3)))
+}
+
+// emailSecret derives a separate key for email verification links so they can
+// never be accepted as access tokens, and the other way around.
+func (j *JWTManager) emailSecret() []byte {
+	return []byte(j.secret + ":email_verification")
+}
+
+func (j *JWTManager) SignEmailVerification(id int, email string, expires time.Duration) (string, error) {
+	token := jwt.NewWithClaims(
+		jwt.SigningMethodHS256,
+		jwt.MapClaims{
+			"id":    id,
+			"email": email,
+			"exp":   time.Now().Add(expires).Unix(),
+			"iat":   time.Now().Unix(),
+		},
+	)
+
+	return token.SignedString(j.emailSecret())
+}
+
+func (j *JWTManager) VerifyEmailVerification(tokenString string) (int, string, error) {
+	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+		return j.emailSecret(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return 0, "", err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return 0, "", errors.New("invalid token")
+	}
+
+	id, _ := claims["id"].(float64)
+	email, _ := claims["email"].(string)
+	if id == 0 || email == "" {
+		return 0, "", errors.New("invalid token")
+	}
+
+	return int(id), email, nil
+}
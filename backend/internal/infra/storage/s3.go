@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Storage talks to any S3-compatible service (AWS S3, MinIO, R2...).
+// Objects are expected to be publicly readable at baseURL; when baseURL is
+// empty the path-style endpoint URL is used.
+type S3Storage struct {
+	client  *minio.Client
+	bucket  string
+	baseURL string
+}
+
+func NewS3(endpoint string, accessKey string, secretKey string, bucket string, region string, useSSL bool, baseURL string) (*S3Storage, error) {
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: useSSL,
+		Region: region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if baseURL == "" {
+		scheme := "http"
+		if useSSL {
+			scheme = "https"
+		}
+		baseURL = fmt.Sprintf("%s://%s/%s", scheme, endpoint, bucket)
+	}
+
+	return &S3Storage{client: client, bucket: bucket, baseURL: strings.TrimRight(baseURL, "/")}, nil
+}
+
+func (s *S3Storage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (string, error) {
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return s.baseURL + "/" + key, nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
@@ -0,0 +1,13 @@
+package storage
+
+import (
+	"context"
+	"io"
+)
+
+// Storage persists uploaded files under a key and hands back the public URL
+// they can be fetched from.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (string, error)
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,179 @@
+// Package validation builds the request validator and turns its errors into
+// per-field details the frontend can show next to each input.
+package validation
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/euandresimoes/ecom-go/backend/internal/models"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	"github.com/go-playground/locales/pt_BR"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	es_translations "github.com/go-playground/validator/v10/translations/es"
+	pt_BR_translations "github.com/go-playground/validator/v10/translations/pt_BR"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// FieldError describes one failed rule. Field is the JSON path of the value,
+// e.g. "price" or "ids[2]".
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// messages for the custom rules, by locale
+var custom = map[string]map[string]string{
+	"en": {
+		"positive":    "{0} must be a positive number",
+		"weight_unit": "{0} must be one of [g kg]",
+	},
+	"pt_BR": {
+		"positive":    "{0} deve ser um número positivo",
+		"weight_unit": "{0} deve ser um de [g kg]",
+	},
+	"es": {
+		"positive":    "{0} debe ser un número positivo",
+		"weight_unit": "{0} debe ser uno de [g kg]",
+	},
+}
+
+var (
+	once     sync.Once
+	validate *validator.Validate
+	uni      *ut.UniversalTranslator
+)
+
+// Validator returns the shared validator. Field names in its errors are the
+// JSON tag names. Translations are registered against the instance, so it is
+// built once and reused.
+func Validator() *validator.Validate {
+	once.Do(func() {
+		validate = validator.New()
+		validate.RegisterTagNameFunc(jsonName)
+		validate.RegisterValidation("positive", positive)
+		validate.RegisterValidation("weight_unit", weightUnit)
+
+		english := en.New()
+		uni = ut.New(english, english, pt_BR.New(), es.New())
+
+		register("en", en_translations.RegisterDefaultTranslations)
+		register("pt_BR", pt_BR_translations.RegisterDefaultTranslations)
+		register("es", es_translations.RegisterDefaultTranslations)
+	})
+
+	return validate
+}
+
+func register(locale string, defaults func(*validator.Validate, ut.Translator) error) {
+	trans, _ := uni.GetTranslator(locale)
+	if err := defaults(validate, trans); err != nil {
+		panic(err)
+	}
+
+	for tag, text := range custom[locale] {
+		err := validate.RegisterTranslation(
+			tag,
+			trans,
+			func(t ut.Translator) error {
+				return t.Add(tag, text, false)
+			},
+			func(t ut.Translator, fe validator.FieldError) string {
+				msg, _ := t.T(fe.Tag(), fe.Field())
+				return msg
+			},
+		)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Fields converts validation errors into field details with messages in the
+// first supported language of the Accept-Language header, English otherwise.
+func Fields(errs validator.ValidationErrors, acceptLanguage string) []FieldError {
+	Validator()
+	trans, _ := uni.FindTranslator(locales(acceptLanguage)...)
+
+	fields := make([]FieldError, 0, len(errs))
+	for _, fe := range errs {
+		fields = append(fields, FieldError{
+			Field:   field(fe.Namespace()),
+			Rule:    fe.Tag(),
+			Param:   fe.Param(),
+			Message: fe.Translate(trans),
+		})
+	}
+
+	return fields
+}
+
+// locales turns "pt-BR,pt;q=0.9,en;q=0.8" into ["pt_BR", "pt", "en"].
+func locales(header string) []string {
+	var list []string
+	for _, part := range strings.Split(header, ",") {
+		tag, _, _ := strings.Cut(part, ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		lang, region, ok := strings.Cut(tag, "-")
+		if ok {
+			tag = strings.ToLower(lang) + "_" + strings.ToUpper(region)
+		}
+		list = append(list, tag)
+	}
+
+	return list
+}
+
+// field drops the struct name the namespace starts with, so
+// "ProductCreateDto.price" becomes "price".
+func field(namespace string) string {
+	_, path, ok := strings.Cut(namespace, ".")
+	if !ok {
+		return namespace
+	}
+
+	return path
+}
+
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return f.Name
+	}
+
+	return name
+}
+
+// positive accepts finite numerics greater than zero. A missing value is not
+// positive either, which makes the rule also stand in for required: the
+// validator skips required on non-pointer structs such as pgtype.Numeric.
+func positive(fl validator.FieldLevel) bool {
+	n, ok := fl.Field().Interface().(pgtype.Numeric)
+	if !ok {
+		return false
+	}
+
+	return n.Valid && !n.NaN && n.InfinityModifier == pgtype.Finite && n.Int != nil && n.Int.Sign() > 0
+}
+
+func weightUnit(fl validator.FieldLevel) bool {
+	switch models.ProductWeightUnit(fl.Field().String()) {
+	case models.ProductUnitG, models.ProductUnitKG:
+		return true
+	default:
+		return false
+	}
+}
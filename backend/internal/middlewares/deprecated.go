@@ -0,0 +1,21 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Deprecated marks every response of a route as deprecated since the given
+// time (RFC 9745), announcing when it stops being served and where its
+// replacement lives.
+func Deprecated(since time.Time, sunset time.Time, successor string) func(http.Handler) http.Handler {
+	return func(n http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", fmt.Sprintf("@%d", since.Unix()))
+			w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+			n.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type CartItemModel struct {
+	ProductID int            `json:"product_id"`
+	VariantID pgtype.Int4    `json:"variant_id"`
+	PublicID  string         `json:"public_id"`
+	Name      string         `json:"name"`
+	SKU       pgtype.Text    `json:"sku"`
+	Price     pgtype.Numeric `json:"price"`
+	Quantity  int            `json:"quantity"`
+	Stock     int            `json:"stock"`
+	LineTotal pgtype.Numeric `json:"line_total"`
+}
+
+type CartModel struct {
+	ID    int             `json:"id"`
+	Items []CartItemModel `json:"items"`
+	Total pgtype.Numeric  `json:"total"`
+}
+
+type CartItemAddDto struct {
+	ProductID int  `json:"product_id" db:"product_id" validate:"required"`
+	VariantID *int `json:"variant_id" db:"variant_id" validate:"omitempty,min=1"`
+	Quantity  int  `json:"quantity" db:"quantity" validate:"required,min=1"`
+}
+
+type CartItemUpdateDto struct {
+	Quantity int `json:"quantity" db:"quantity" validate:"required,min=1"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type OrderStatus string
+
+const (
+	OrderPending   OrderStatus = "pending"
+	OrderPaid      OrderStatus = "paid"
+	OrderShipped   OrderStatus = "shipped"
+	OrderDelivered OrderStatus = "delivered"
+	OrderCancelled OrderStatus = "cancelled"
+	OrderRefunded  OrderStatus = "refunded"
+)
+
+type OrderItemModel struct {
+	ProductID   pgtype.Int4    `json:"product_id"`
+	VariantID   pgtype.Int4    `json:"variant_id"`
+	ProductName string         `json:"product_name"`
+	SKU         pgtype.Text    `json:"sku"`
+	UnitPrice   pgtype.Numeric `json:"unit_price"`
+	Quantity    int            `json:"quantity"`
+	LineTotal   pgtype.Numeric `json:"line_total"`
+}
+
+type OrderModel struct {
+	ID        int                `json:"id"`
+	PublicID  string             `json:"public_id"`
+	UserID    int                `json:"user_id"`
+	Status    OrderStatus        `json:"status"`
+	Total     pgtype.Numeric     `json:"total"`
+	Items     []OrderItemModel   `json:"items"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type OrderStatusHistoryModel struct {
+	FromStatus *OrderStatus       `json:"from_status"`
+	ToStatus   OrderStatus        `json:"to_status"`
+	ChangedBy  pgtype.Int4        `json:"changed_by"`
+	Note       pgtype.Text        `json:"note"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type OrderTransitionDto struct {
+	Status OrderStatus `json:"status" db:"status" validate:"required,oneof=pending paid shipped delivered cancelled refunded"`
+	Note   *string     `json:"note" db:"note" validate:"omitempty,max=255"`
+}
@@ -23,25 +23,27 @@ type ProductModel struct {
 	Images      []string           `json:"images"`
 	CreatedAt   pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+
+	Variants []ProductVariantModel `json:"variants,omitempty"`
 }
 
 type ProductCreateDto struct {
 	Name        string            `json:"name" db:"name" validate:"required,min=3,max=30"`
-	Price       pgtype.Numeric    `json:"price" db:"price" validate:"required"`
+	Price       pgtype.Numeric    `json:"price" db:"price" validate:"required,positive"`
 	Stock       int               `json:"stock" db:"stock" validate:"required"`
 	CategoryID  int               `json:"category_id" db:"category_id" validate:"required"`
-	WeightUnit  ProductWeightUnit `json:"weight_unit" db:"weight_unit" validate:"required"`
-	WeightValue pgtype.Numeric    `json:"weight_value" db:"weight_value" validate:"required"`
+	WeightUnit  ProductWeightUnit `json:"weight_unit" db:"weight_unit" validate:"required,weight_unit"`
+	WeightValue pgtype.Numeric    `json:"weight_value" db:"weight_value" validate:"required,positive"`
 	Images      []string          `json:"images" db:"images" validate:"required"`
 }
 
 type ProductUpdateDto struct {
 	Name        *string            `json:"name" db:"name" validate:"omitempty,min=3,max=30"`
-	Price       *pgtype.Numeric    `json:"price" db:"price" validate:"omitempty"`
+	Price       *pgtype.Numeric    `json:"price" db:"price" validate:"omitempty,positive"`
 	Stock       *int               `json:"stock" db:"stock" validate:"omitempty"`
 	CategoryID  *int               `json:"category_id" db:"category_id" validate:"omitempty"`
-	WeightUnit  *ProductWeightUnit `json:"weight_unit" db:"weight_unit" validate:"omitempty"`
-	WeightValue *pgtype.Numeric    `json:"weight_value" db:"weight_value" validate:"omitempty"`
+	WeightUnit  *ProductWeightUnit `json:"weight_unit" db:"weight_unit" validate:"omitempty,weight_unit"`
+	WeightValue *pgtype.Numeric    `json:"weight_value" db:"weight_value" validate:"omitempty,positive"`
 	Images      *[]string          `json:"images" db:"images" validate:"omitempty"`
 }
 
@@ -53,3 +55,77 @@ type CategoryModel struct {
 type CategoryCreateDto struct {
 	Name string `json:"name" db:"name" validate:"required,min=3,max=50"`
 }
+
+type ProductListQuery struct {
+	CategoryID *int    `json:"category_id,omitempty" validate:"omitempty,min=1"`
+	MinPrice   *string `json:"min_price,omitempty" validate:"omitempty,numeric"`
+	MaxPrice   *string `json:"max_price,omitempty" validate:"omitempty,numeric"`
+	InStock    bool    `json:"in_stock,omitempty"`
+	Sort       string  `json:"sort" validate:"oneof=price name created_at"`
+	Order      string  `json:"order" validate:"oneof=asc desc"`
+	Limit      int     `json:"limit" validate:"min=1,max=100"`
+	Page       int     `json:"page,omitempty" validate:"omitempty,min=1"`
+	Cursor     string  `json:"cursor,omitempty"`
+}
+
+type ProductListModel struct {
+	Products   []ProductModel `json:"products"`
+	NextCursor *string        `json:"next_cursor"`
+	Total      int            `json:"total"`
+	Limit      int            `json:"limit"`
+	Page       int            `json:"page,omitempty"`
+}
+
+type ProductSearchResultModel struct {
+	ProductModel
+	Rank      float32 `json:"rank"`
+	Highlight string  `json:"highlight"`
+}
+
+type ProductVariantModel struct {
+	ID          pgtype.Int4        `json:"id"`
+	ProductID   int                `json:"product_id"`
+	SKU         string             `json:"sku"`
+	Options     map[string]string  `json:"options"`
+	Price       pgtype.Numeric     `json:"price"`
+	Stock       int                `json:"stock"`
+	WeightUnit  ProductWeightUnit  `json:"weight_unit"`
+	WeightValue pgtype.Numeric     `json:"weight_value"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+}
+
+type ProductVariantCreateDto struct {
+	SKU         string            `json:"sku" db:"sku" validate:"required,min=1,max=64"`
+	Options     map[string]string `json:"options" db:"options" validate:"omitempty,dive,keys,min=1,max=30,endkeys,min=1,max=50"`
+	Price       pgtype.Numeric    `json:"price" db:"price" validate:"required,positive"`
+	Stock       int               `json:"stock" db:"stock" validate:"min=0"`
+	WeightUnit  ProductWeightUnit `json:"weight_unit" db:"weight_unit" validate:"required,weight_unit"`
+	WeightValue pgtype.Numeric    `json:"weight_value" db:"weight_value" validate:"required,positive"`
+}
+
+type ProductVariantUpdateDto struct {
+	SKU         *string            `json:"sku" db:"sku" validate:"omitempty,min=1,max=64"`
+	Options     *map[string]string `json:"options" db:"options" validate:"omitempty"`
+	Price       *pgtype.Numeric    `json:"price" db:"price" validate:"omitempty,positive"`
+	Stock       *int               `json:"stock" db:"stock" validate:"omitempty,min=0"`
+	WeightUnit  *ProductWeightUnit `json:"weight_unit" db:"weight_unit" validate:"omitempty,weight_unit"`
+	WeightValue *pgtype.Numeric    `json:"weight_value" db:"weight_value" validate:"omitempty,positive"`
+}
+
+type ProductImageModel struct {
+	ID           int                `json:"id"`
+	ProductID    int                `json:"product_id"`
+	StorageKey   string             `json:"-"`
+	ThumbnailKey string             `json:"-"`
+	URL          string             `json:"url"`
+	ThumbnailURL string             `json:"thumbnail_url"`
+	ContentType  string             `json:"content_type"`
+	Size         int                `json:"size"`
+	Position     int                `json:"position"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+}
+
+type ProductImageOrderDto struct {
+	IDs []int `json:"ids" validate:"required,min=1,dive,min=1"`
+}
@@ -12,20 +12,22 @@ const (
 )
 
 type UserModel struct {
-	ID           int                `json:"id"`
-	FirstName    string             `json:"first_name"`
-	LastName     string             `json:"last_name"`
-	Email        string             `json:"email"`
-	PasswordHash string             `json:"password_hash"`
-	Role         UserRole           `json:"role"`
-	CreatedAt    pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+	ID              int                `json:"id"`
+	FirstName       string             `json:"first_name"`
+	LastName        string             `json:"last_name"`
+	Email           string             `json:"email"`
+	PasswordHash    string             `json:"password_hash"`
+	Role            UserRole           `json:"role"`
+	EmailVerifiedAt pgtype.Timestamptz `json:"email_verified_at"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
 }
 
 type UserPublicModel struct {
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
-	Email     string `json:"email"`
+	FirstName       string             `json:"first_name"`
+	LastName        string             `json:"last_name"`
+	Email           string             `json:"email"`
+	EmailVerifiedAt pgtype.Timestamptz `json:"email_verified_at"`
 }
 
 type UserRegisterModel struct {
@@ -41,8 +43,59 @@ type UserLoginModel struct {
 }
 
 type UserUpdateModel struct {
-	FirstName   *string `json:"first_name" db:"first_name" validate:"omitempty,min=3,max=15"`
-	LastName    *string `json:"last_name" db:"last_name" validate:"omitempty,min=3,max=15"`
-	Email       *string `json:"email" db:"email" validate:"omitempty,email,min=5,max=50"`
-	NewPassword *string `json:"new_password" db:"password_hash" validate:"omitempty,min=8,max=32"`
+	FirstName       *string `json:"first_name" db:"first_name" validate:"omitempty,min=3,max=15"`
+	LastName        *string `json:"last_name" db:"last_name" validate:"omitempty,min=3,max=15"`
+	Email           *string `json:"email" db:"email" validate:"omitempty,email,min=5,max=50"`
+	NewPassword     *string `json:"new_password" db:"password_hash" validate:"omitempty,min=8,max=32"`
+	CurrentPassword *string `json:"current_password" validate:"omitempty,max=32"`
+}
+
+type UserDeleteModel struct {
+	Password string `json:"password" validate:"required,max=32"`
+}
+
+type UserRefreshModel struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type TokenPairModel struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type UserForgotPasswordModel struct {
+	Email string `json:"email" db:"email" validate:"required,email,max=50"`
+}
+
+type UserResetPasswordModel struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" db:"password_hash" validate:"required,min=8,max=32"`
+}
+
+type UserResendVerificationModel struct {
+	Email string `json:"email" db:"email" validate:"required,email,max=50"`
+}
+
+type UserAdminModel struct {
+	ID              int                `json:"id"`
+	FirstName       string             `json:"first_name"`
+	LastName        string             `json:"last_name"`
+	Email           string             `json:"email"`
+	Role            UserRole           `json:"role"`
+	EmailVerifiedAt pgtype.Timestamptz `json:"email_verified_at"`
+	DisabledAt      pgtype.Timestamptz `json:"disabled_at"`
+	DeletedAt       pgtype.Timestamptz `json:"deleted_at"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+}
+
+type UserListModel struct {
+	Users []UserAdminModel `json:"users"`
+	Total int              `json:"total"`
+	Page  int              `json:"page"`
+	Limit int              `json:"limit"`
+}
+
+type UserRoleUpdateModel struct {
+	Role UserRole `json:"role" db:"role" validate:"required,oneof=customer admin"`
 }
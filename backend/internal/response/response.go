@@ -0,0 +1,117 @@
+// Package response writes error responses as RFC 7807 problem details.
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/euandresimoes/ecom-go/backend/internal/errs"
+	"github.com/euandresimoes/ecom-go/backend/internal/infra/validation"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// StatusClientClosedRequest is the non-standard status nginx uses for requests
+// the client abandoned before the response was written.
+const StatusClientClosedRequest = 499
+
+// ProblemModel is an RFC 7807 problem details object.
+type ProblemModel struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	// Errors lists the invalid fields of a 422 response.
+	Errors []validation.FieldError `json:"errors,omitempty"`
+}
+
+var statuses = []struct {
+	kind   error
+	status int
+}{
+	{errs.ErrBadRequest, http.StatusBadRequest},
+	{errs.ErrNotFound, http.StatusNotFound},
+	{errs.ErrConflict, http.StatusConflict},
+	{errs.ErrValidation, http.StatusUnprocessableEntity},
+	{errs.ErrUnauthorized, http.StatusUnauthorized},
+	{errs.ErrForbidden, http.StatusForbidden},
+	{errs.ErrUnsupported, http.StatusUnsupportedMediaType},
+}
+
+// Problem writes a problem details response with the given status.
+func Problem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	write(w, newProblem(r, status, detail))
+}
+
+func newProblem(r *http.Request, status int, detail string) ProblemModel {
+	return ProblemModel{
+		Type:     "about:blank",
+		Title:    title(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+	}
+}
+
+func title(status int) string {
+	if status == StatusClientClosedRequest {
+		return "Client Closed Request"
+	}
+
+	return http.StatusText(status)
+}
+
+func write(w http.ResponseWriter, p ProblemModel) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// Error maps err to a status code by its errs kind and writes it as a
+// problem. Cancelled requests answer 499 and timed out ones 504, whether the
+// request deadline or Postgres' statement_timeout ran out. Errors without a
+// known kind are logged and answered with a bare 500 so database or network
+// details never reach the client.
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	var verr validator.ValidationErrors
+	if errors.As(err, &verr) {
+		p := newProblem(r, http.StatusUnprocessableEntity, "one or more fields are invalid")
+		p.Errors = validation.Fields(verr, r.Header.Get("Accept-Language"))
+		write(w, p)
+		return
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		Problem(w, r, StatusClientClosedRequest, "client closed request")
+		return
+	case errors.Is(err, context.DeadlineExceeded) || statementTimeout(err):
+		Problem(w, r, http.StatusGatewayTimeout, "request timed out")
+		return
+	}
+
+	var e *errs.Error
+	if errors.As(err, &e) {
+		for _, s := range statuses {
+			if errors.Is(e.Kind, s.kind) {
+				Problem(w, r, s.status, err.Error())
+				return
+			}
+		}
+	}
+
+	slog.ErrorContext(r.Context(), "request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+	Problem(w, r, http.StatusInternalServerError, "internal server error")
+}
+
+// statementTimeout reports whether Postgres cancelled the query for running
+// past statement_timeout.
+func statementTimeout(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "57014"
+}
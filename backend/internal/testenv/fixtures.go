@@ -0,0 +1,66 @@
+package testenv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lucsky/cuid"
+)
+
+// SeedUser inserts a customer with a unique email and returns its id.
+func SeedUser(t testing.TB, db *pgxpool.Pool) int {
+	t.Helper()
+
+	var id int
+
+	query := `
+		INSERT INTO users (first_name, email, password_hash)
+		VALUES ('Alice', $1, 'x')
+		RETURNING id
+	`
+	if err := db.QueryRow(context.Background(), query, cuid.New()+"@example.com").Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+// SeedProduct inserts a product in a category of its own and returns its id.
+func SeedProduct(t testing.TB, db *pgxpool.Pool, name string, price string, stock int) int {
+	t.Helper()
+
+	var id int
+
+	query := `
+		WITH c AS (
+			INSERT INTO categories (name) VALUES ($1) RETURNING id
+		)
+		INSERT INTO products (public_id, name, price, stock, category_id, weight_unit, weight_value)
+		SELECT $1, $2, $3::numeric, $4, c.id, 'g', 250 FROM c
+		RETURNING id
+	`
+	if err := db.QueryRow(context.Background(), query, cuid.New(), name, price, stock).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+// SeedVariant inserts a variant of the product and returns its id.
+func SeedVariant(t testing.TB, db *pgxpool.Pool, productID int, sku string, price string, stock int) int {
+	t.Helper()
+
+	var id int
+
+	query := `
+		INSERT INTO product_variants (product_id, sku, price, stock, weight_unit, weight_value)
+		VALUES ($1, $2, $3::numeric, $4, 'kg', 1)
+		RETURNING id
+	`
+	if err := db.QueryRow(context.Background(), query, productID, sku, price, stock).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}